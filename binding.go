@@ -0,0 +1,40 @@
+package pagination
+
+import (
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// binding returns a fingerprint of the parts of the request that page tokens are bound to,
+// or nil if tokens are not bound.
+func (m *middleware) binding(r *http.Request, u url.URL) []byte {
+	if !m.bindQuery && !m.bindPath && m.principal == nil {
+		return nil
+	}
+
+	h := sha256.New()
+
+	// the encoded query and path escape NUL, so it can be used as a separator
+	// the principal is length prefixed instead, as it may contain anything
+	if m.bindQuery {
+		q := u.Query()
+		q.Del(m.params.MaxItems)
+		q.Del(m.params.Page)
+
+		io.WriteString(h, "query\x00"+q.Encode()+"\x00")
+	}
+
+	if m.bindPath {
+		io.WriteString(h, "path\x00"+u.EscapedPath()+"\x00")
+	}
+
+	if m.principal != nil {
+		principal := m.principal(r)
+		io.WriteString(h, "principal\x00"+strconv.Itoa(len(principal))+"\x00"+principal)
+	}
+
+	return h.Sum(nil)
+}
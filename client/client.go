@@ -0,0 +1,226 @@
+// Package client implements the client side of the pagination specification,
+// requesting each page in turn by following rel="next" links until none remain.
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/JoeReid/pagination"
+)
+
+// StatusError is returned when the server responds to a page request with a non 2xx status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("pagination: GET %s: unexpected status %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Iterator requests the pages of a paginated resource, one at a time.
+//
+//	it := client.NewIterator(http.DefaultClient, "https://example.com/items", client.WithMaxItems(50))
+//	defer it.Close()
+//
+//	for it.Next(ctx) {
+//		resp := it.Response()
+//		// decode resp.Body
+//	}
+//
+//	if err := it.Err(); err != nil {
+//		// handle err
+//	}
+type Iterator struct {
+	client          *http.Client
+	next            string
+	resp            *http.Response
+	err             error
+	params          pagination.Params
+	maxItems        int
+	onDeprecation   func(Deprecation)
+	followAlternate bool
+	prefetch        int
+	pages           chan page
+	cancel          context.CancelFunc
+}
+
+// NewIterator returns an Iterator over the pages of the resource at startURL, using the given client.
+func NewIterator(c *http.Client, startURL string, opts ...Option) *Iterator {
+	it := &Iterator{
+		client: c,
+		next:   startURL,
+		params: pagination.DefaultParams,
+	}
+
+	for _, opt := range opts {
+		opt(it)
+	}
+
+	if it.maxItems > 0 {
+		it.setMaxItems()
+	}
+
+	return it
+}
+
+type Option func(*Iterator)
+
+// WithMaxItems requests the given number of items per page, by setting maxItems on the starting URL,
+// or the parameter named by WithParams. The server preserves it in the links to subsequent pages.
+func WithMaxItems(maxItems int) Option {
+	return func(it *Iterator) {
+		it.maxItems = maxItems
+	}
+}
+
+// WithParams sets the query parameter names used by the server, in place of pagination.DefaultParams,
+// for servers that rename them with pagination.WithParams. Empty names are left at their default.
+func WithParams(p pagination.Params) Option {
+	return func(it *Iterator) {
+		if p.MaxItems != "" {
+			it.params.MaxItems = p.MaxItems
+		}
+
+		if p.Page != "" {
+			it.params.Page = p.Page
+		}
+	}
+}
+
+func (it *Iterator) setMaxItems() {
+	u, err := url.Parse(it.next)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	q := u.Query()
+	q.Set(it.params.MaxItems, strconv.Itoa(it.maxItems))
+
+	u.RawQuery = q.Encode()
+	it.next = u.String()
+}
+
+// Next requests the next page, returning false when there are no more pages or an error occurred.
+// The body of the previous page is closed.
+//
+// Pagination has finished when a response has no rel="next" link.
+func (it *Iterator) Next(ctx context.Context) bool {
+	it.closeResponse()
+
+	if it.err != nil || (it.pages == nil && it.next == "") {
+		return false
+	}
+
+	if it.prefetch > 0 {
+		return it.nextPrefetched(ctx)
+	}
+
+	it.resp, it.next, it.err = it.fetch(ctx, it.next)
+	return it.resp != nil
+}
+
+// fetch requests the page at u, returning it along with the URL of the following page, if there is one.
+// The page is still returned with an error if the link to the following page can't be read.
+func (it *Iterator) fetch(ctx context.Context, u string) (*http.Response, string, error) {
+	resp, base, err := it.get(ctx, u)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if d, ok := deprecation(resp, base); ok {
+		if it.onDeprecation != nil {
+			it.onDeprecation(d)
+		}
+
+		// the response from the alternate URL isn't checked again, so it is followed at most once
+		if it.followAlternate && d.Alternate != "" {
+			drain(resp)
+
+			if resp, base, err = it.get(ctx, d.Alternate); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	next, ok, err := findLink(resp.Header, "next")
+	if err != nil || !ok {
+		return resp, "", err
+	}
+
+	// links may be relative to the URL of the page they were returned with
+	nextURL, err := base.Parse(next)
+	if err != nil {
+		return resp, "", err
+	}
+
+	return resp, nextURL.String(), nil
+}
+
+// get requests the page at u, returning it along with the URL that links in it are relative to.
+func (it *Iterator) get(ctx context.Context, u string) (*http.Response, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := it.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, nil, &StatusError{URL: u, StatusCode: resp.StatusCode}
+	}
+
+	// the response holds the request that was finally made, after any redirects, but a RoundTripper
+	// used directly by the client need not set it
+	base := req.URL
+	if resp.Request != nil {
+		base = resp.Request.URL
+	}
+
+	return resp, base, nil
+}
+
+// Response returns the response for the current page. Its body is closed by the following call to Next or Close.
+func (it *Iterator) Response() *http.Response {
+	return it.resp
+}
+
+// Err returns the first error encountered while paginating, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close closes the body of the current page, and stops any further pages being requested.
+func (it *Iterator) Close() error {
+	it.next = ""
+	it.stopPrefetch()
+
+	return it.closeResponse()
+}
+
+func (it *Iterator) closeResponse() error {
+	if it.resp == nil {
+		return nil
+	}
+
+	err := drain(it.resp)
+
+	it.resp = nil
+	return err
+}
+
+// drain reads the rest of the body before closing it, so the connection can be reused.
+func drain(resp *http.Response) error {
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
@@ -0,0 +1,544 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JoeReid/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer serves the integers [0, total) as JSON arrays, paginated with the pagination middleware.
+func newTestServer(t *testing.T, total int, opts ...pagination.MiddlewareOpt) *httptest.Server {
+	middleware := pagination.NewMiddleware(append([]pagination.MiddlewareOpt{pagination.WithMaxItemsDefault(10)}, opts...)...)
+
+	srv := httptest.NewServer(middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, err := pagination.Offset(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		items := []int{}
+		for i := offset; i < total && i < offset+pagination.MaxItems(r); i++ {
+			items = append(items, i)
+		}
+
+		if offset+len(items) < total {
+			pagination.SetNext(r, pagination.OffsetToken(offset+len(items)))
+		}
+
+		json.NewEncoder(w).Encode(items)
+	})))
+
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestIterator(t *testing.T) {
+	tests := []struct {
+		name        string
+		total       int
+		serverOpts  []pagination.MiddlewareOpt
+		opts        []Option
+		expectPages [][]int
+	}{
+		{
+			name:        "single page",
+			total:       3,
+			opts:        []Option{},
+			expectPages: [][]int{{0, 1, 2}},
+		},
+		{
+			name:        "default max items",
+			total:       25,
+			opts:        []Option{},
+			expectPages: [][]int{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, {10, 11, 12, 13, 14, 15, 16, 17, 18, 19}, {20, 21, 22, 23, 24}},
+		},
+		{
+			name:        "with max items",
+			total:       7,
+			opts:        []Option{WithMaxItems(3)},
+			expectPages: [][]int{{0, 1, 2}, {3, 4, 5}, {6}},
+		},
+		{
+			name:        "renamed params",
+			total:       7,
+			serverOpts:  []pagination.MiddlewareOpt{pagination.WithParams(pagination.Params{MaxItems: "page_size", Page: "page_token"})},
+			opts:        []Option{WithMaxItems(3), WithParams(pagination.Params{MaxItems: "page_size"})},
+			expectPages: [][]int{{0, 1, 2}, {3, 4, 5}, {6}},
+		},
+		{
+			name:        "empty",
+			total:       0,
+			opts:        []Option{},
+			expectPages: [][]int{{}},
+		},
+		{
+			name:        "prefetch",
+			total:       25,
+			opts:        []Option{WithPrefetch(2)},
+			expectPages: [][]int{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, {10, 11, 12, 13, 14, 15, 16, 17, 18, 19}, {20, 21, 22, 23, 24}},
+		},
+		{
+			name:        "prefetch single page",
+			total:       3,
+			opts:        []Option{WithPrefetch(1)},
+			expectPages: [][]int{{0, 1, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				srv   = newTestServer(t, tt.total, tt.serverOpts...)
+				it    = NewIterator(srv.Client(), srv.URL+"/items", tt.opts...)
+				pages [][]int
+			)
+
+			defer it.Close()
+
+			for it.Next(context.Background()) {
+				var page []int
+				require.NoError(t, json.NewDecoder(it.Response().Body).Decode(&page))
+
+				pages = append(pages, page)
+			}
+
+			require.NoError(t, it.Err())
+			assert.Equal(t, tt.expectPages, pages)
+		})
+	}
+}
+
+func TestIteratorErrors(t *testing.T) {
+	t.Run("status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusNotFound)
+		}))
+		defer srv.Close()
+
+		it := NewIterator(srv.Client(), srv.URL)
+		assert.False(t, it.Next(context.Background()))
+
+		var statusErr *StatusError
+		require.ErrorAs(t, it.Err(), &statusErr)
+		assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		srv := newTestServer(t, 25)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		it := NewIterator(srv.Client(), srv.URL)
+		require.True(t, it.Next(ctx))
+
+		cancel()
+		assert.False(t, it.Next(ctx))
+		assert.ErrorIs(t, it.Err(), context.Canceled)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		srv := newTestServer(t, 25)
+
+		it := NewIterator(srv.Client(), srv.URL)
+		require.True(t, it.Next(context.Background()))
+
+		body := it.Response().Body
+		require.NoError(t, it.Close())
+
+		_, err := body.Read(make([]byte, 1))
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, io.EOF)
+
+		assert.False(t, it.Next(context.Background()))
+		assert.NoError(t, it.Err())
+	})
+}
+
+func TestRelativeLinks(t *testing.T) {
+	// the transport answers without setting the request on its responses, which the client leaves unset
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("[]"))}
+		if r.URL.Query().Get("page") == "" {
+			resp.Header.Set("Link", `<?page=abc>; rel="next"`)
+		}
+
+		return resp, nil
+	})}
+
+	it := NewIterator(client, "https://example.com/items")
+	defer it.Close()
+
+	var pages []string
+	for it.Next(context.Background()) {
+		pages = append(pages, it.Response().Header.Get("Link"))
+	}
+
+	require.NoError(t, it.Err())
+	assert.Len(t, pages, 2)
+}
+
+func TestFindLink(t *testing.T) {
+	tests := []struct {
+		name       string
+		links      []string
+		expectNext string
+		expectOK   bool
+		expectErr  bool
+	}{
+		{
+			name:     "no links",
+			links:    nil,
+			expectOK: false,
+		},
+		{
+			name:       "next",
+			links:      []string{`<https://example.com/items?page=abc>; rel="next"`},
+			expectNext: "https://example.com/items?page=abc",
+			expectOK:   true,
+		},
+		{
+			name:       "several headers",
+			links:      []string{`<https://example.com/items?page=abc>; rel="prev"`, `<https://example.com/items?page=def>; rel="next"`},
+			expectNext: "https://example.com/items?page=def",
+			expectOK:   true,
+		},
+		{
+			name:       "comma separated",
+			links:      []string{`<https://example.com/a,b>; rel="prev"; title="a, b", <https://example.com/items?page=def>; rel=next`},
+			expectNext: "https://example.com/items?page=def",
+			expectOK:   true,
+		},
+		{
+			name:       "multiple rel values",
+			links:      []string{`<https://example.com/items?page=def>; rel="last next"`},
+			expectNext: "https://example.com/items?page=def",
+			expectOK:   true,
+		},
+		{
+			name:     "prev only",
+			links:    []string{`<https://example.com/items?page=abc>; rel="prev"`},
+			expectOK: false,
+		},
+		{
+			name:      "malformed",
+			links:     []string{`<https://example.com/items?page=abc; rel="next"`},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, ok, err := findLink(http.Header{"Link": tt.links}, "next")
+			if tt.expectErr {
+				assert.ErrorIs(t, err, pagination.ErrInvalidLink)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectOK, ok)
+			assert.Equal(t, tt.expectNext, next)
+		})
+	}
+}
+
+func TestAll(t *testing.T) {
+	t.Run("every item", func(t *testing.T) {
+		srv := newTestServer(t, 25)
+
+		var items []int
+		for item, err := range All(context.Background(), srv.Client(), srv.URL, DecodeJSON[int], WithMaxItems(7)) {
+			require.NoError(t, err)
+			items = append(items, item)
+		}
+
+		require.Len(t, items, 25)
+		for i, item := range items {
+			assert.Equal(t, i, item)
+		}
+	})
+
+	t.Run("early break", func(t *testing.T) {
+		var (
+			srv      = newTestServer(t, 25)
+			requests int
+			client   = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				requests++
+				return http.DefaultTransport.RoundTrip(r)
+			})}
+		)
+
+		for item, err := range All(context.Background(), client, srv.URL, DecodeJSON[int]) {
+			require.NoError(t, err)
+
+			if item == 12 {
+				break
+			}
+		}
+
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("decode error", func(t *testing.T) {
+		srv := newTestServer(t, 25)
+
+		var (
+			items []int
+			errs  []error
+		)
+
+		decode := func(resp *http.Response) ([]int, error) {
+			if resp.Request.URL.Query().Get("page") != "" {
+				return nil, assert.AnError
+			}
+
+			return DecodeJSON[int](resp)
+		}
+
+		for item, err := range All(context.Background(), srv.Client(), srv.URL, decode) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			items = append(items, item)
+		}
+
+		assert.Len(t, items, 10)
+		assert.Equal(t, []error{assert.AnError}, errs)
+	})
+
+	t.Run("request error", func(t *testing.T) {
+		srv := newTestServer(t, 25)
+		srv.Close()
+
+		var errs []error
+		for _, err := range All(context.Background(), srv.Client(), srv.URL, DecodeJSON[int]) {
+			errs = append(errs, err)
+		}
+
+		assert.Len(t, errs, 1)
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestDeprecation(t *testing.T) {
+	newLegacyServer := func(t *testing.T) *httptest.Server {
+		return newTestServer(t, 25,
+			pagination.WithLegacyScheme("offset", pagination.OffsetLimitRewriter(pagination.DefaultParams)),
+			pagination.WithLegacyResponder("offset", pagination.OffsetLimitResponder(pagination.DefaultParams)),
+		)
+	}
+
+	tests := []struct {
+		name               string
+		opts               []Option
+		expectDeprecations int
+		expectRequests     []string
+	}{
+		{
+			name:               "reported",
+			opts:               []Option{},
+			expectDeprecations: 3,
+			expectRequests:     []string{"offset=0&limit=10", "limit=10&offset=10", "limit=10&offset=20"},
+		},
+		{
+			name:               "followed",
+			opts:               []Option{WithFollowAlternate()},
+			expectDeprecations: 1,
+			expectRequests: []string{
+				"offset=0&limit=10",
+				"maxItems=10",
+				"maxItems=10&page=" + url.QueryEscape(pagination.OffsetToken(10)),
+				"maxItems=10&page=" + url.QueryEscape(pagination.OffsetToken(20)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				srv          = newLegacyServer(t)
+				requests     []string
+				deprecations []Deprecation
+				client       = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					requests = append(requests, r.URL.RawQuery)
+					return http.DefaultTransport.RoundTrip(r)
+				})}
+			)
+
+			opts := append(tt.opts, WithDeprecationHandler(func(d Deprecation) {
+				deprecations = append(deprecations, d)
+			}))
+
+			var items []int
+			for item, err := range All(context.Background(), client, srv.URL+"/items?offset=0&limit=10", DecodeJSON[int], opts...) {
+				require.NoError(t, err)
+				items = append(items, item)
+			}
+
+			assert.Len(t, items, 25)
+			assert.Equal(t, tt.expectRequests, requests)
+
+			require.Len(t, deprecations, tt.expectDeprecations)
+			assert.Equal(t, srv.URL+"/items?offset=0&limit=10", deprecations[0].URL)
+			assert.Equal(t, srv.URL+"/items?maxItems=10", deprecations[0].Alternate)
+			assert.NotEmpty(t, deprecations[0].Warning)
+		})
+	}
+}
+
+func TestDeprecationRelativeAlternate(t *testing.T) {
+	// the transport answers without setting the request on its responses, which the client leaves unset
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("[]"))}
+		if r.URL.Query().Has("offset") {
+			resp.Header.Set("Warning", `299 - "Deprecated"`)
+			resp.Header.Set("Link", `</items?maxItems=10>; rel="alternate"`)
+		}
+
+		return resp, nil
+	})}
+
+	var deprecations []Deprecation
+
+	it := NewIterator(client, "https://example.com/items?offset=0", WithFollowAlternate(), WithDeprecationHandler(func(d Deprecation) {
+		deprecations = append(deprecations, d)
+	}))
+	defer it.Close()
+
+	require.True(t, it.Next(context.Background()))
+	assert.Empty(t, it.Response().Header.Get("Warning"))
+
+	require.Len(t, deprecations, 1)
+	assert.Equal(t, "https://example.com/items?offset=0", deprecations[0].URL)
+	assert.Equal(t, "https://example.com/items?maxItems=10", deprecations[0].Alternate)
+}
+
+func TestLegacyWarning(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		expectText string
+		expectOK   bool
+	}{
+		{name: "legacy", value: `299 - "Deprecated"`, expectText: "Deprecated", expectOK: true},
+		{name: "escaped", value: `299 example.com "say \"hi\"" "Sat, 01 Jan 2000 00:00:00 GMT"`, expectText: `say "hi"`, expectOK: true},
+		{name: "other code", value: `199 - "Miscellaneous"`},
+		{name: "unquoted", value: `299 - Deprecated`},
+		{name: "unterminated", value: `299 - "Deprecated`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, ok := legacyWarning(tt.value)
+			assert.Equal(t, tt.expectOK, ok)
+			assert.Equal(t, tt.expectText, text)
+		})
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	// countRequests returns a client that counts its requests, failing with a 500 status from the given request on
+	countRequests := func(requests *atomic.Int32, failFrom int32) *http.Client {
+		return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			if requests.Add(1) >= failFrom {
+				return &http.Response{StatusCode: http.StatusInternalServerError, Body: http.NoBody, Request: r}, nil
+			}
+
+			return http.DefaultTransport.RoundTrip(r)
+		})}
+	}
+
+	t.Run("bounded lookahead", func(t *testing.T) {
+		var (
+			srv      = newTestServer(t, 100)
+			requests atomic.Int32
+			it       = NewIterator(countRequests(&requests, 1000), srv.URL, WithMaxItems(1), WithPrefetch(3))
+		)
+
+		defer it.Close()
+
+		require.True(t, it.Next(context.Background()))
+
+		assert.Eventually(t, func() bool { return requests.Load() == 4 }, time.Second, time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, int32(4), requests.Load())
+
+		require.True(t, it.Next(context.Background()))
+		assert.Eventually(t, func() bool { return requests.Load() == 5 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("errors in order", func(t *testing.T) {
+		var (
+			srv      = newTestServer(t, 100)
+			requests atomic.Int32
+			it       = NewIterator(countRequests(&requests, 3), srv.URL, WithPrefetch(5))
+			pages    [][]int
+		)
+
+		defer it.Close()
+
+		for it.Next(context.Background()) {
+			var page []int
+			require.NoError(t, json.NewDecoder(it.Response().Body).Decode(&page))
+
+			pages = append(pages, page)
+		}
+
+		assert.Len(t, pages, 2)
+
+		var statusErr *StatusError
+		require.ErrorAs(t, it.Err(), &statusErr)
+		assert.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		srv := newTestServer(t, 100)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		it := NewIterator(srv.Client(), srv.URL, WithPrefetch(2))
+		defer it.Close()
+
+		require.True(t, it.Next(ctx))
+
+		cancel()
+		assert.False(t, it.Next(ctx))
+		assert.ErrorIs(t, it.Err(), context.Canceled)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		var (
+			srv      = newTestServer(t, 100)
+			requests atomic.Int32
+			it       = NewIterator(countRequests(&requests, 1000), srv.URL, WithMaxItems(1), WithPrefetch(3))
+		)
+
+		require.True(t, it.Next(context.Background()))
+		require.NoError(t, it.Close())
+
+		stopped := requests.Load()
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, stopped, requests.Load())
+
+		assert.False(t, it.Next(context.Background()))
+		assert.NoError(t, it.Err())
+	})
+}
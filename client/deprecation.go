@@ -0,0 +1,104 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Deprecation describes a page returned for a request using a legacy pagination method.
+//
+// The server signals this with a Warning: 299 header, and a rel="alternate" link to the
+// equivalent request in the specification's format.
+type Deprecation struct {
+	// URL is the legacy URL that was requested.
+	URL string
+
+	// Warning is the text of the warning sent by the server.
+	Warning string
+
+	// Alternate is the equivalent URL in the specification's format, or empty if the server didn't send one.
+	Alternate string
+
+	// Header holds the response headers, which may include Deprecation and Sunset headers.
+	Header http.Header
+}
+
+// WithDeprecationHandler registers a function that is called for every page returned for a legacy request,
+// so that the use of legacy URLs can be logged and migrated away from.
+func WithDeprecationHandler(handler func(Deprecation)) Option {
+	return func(it *Iterator) {
+		it.onDeprecation = handler
+	}
+}
+
+// WithFollowAlternate switches from a legacy URL to the alternate URL in the specification's format
+// as soon as the server offers one. The page is requested again from the alternate URL, so that it,
+// and the links to every following page, are in the specification's format.
+//
+// The alternate URL is followed at most once per page.
+func WithFollowAlternate() Option {
+	return func(it *Iterator) {
+		it.followAlternate = true
+	}
+}
+
+// deprecation reports whether the response to a request for u is to a legacy request, with the alternate URL
+// resolved against u.
+func deprecation(resp *http.Response, u *url.URL) (Deprecation, bool) {
+	for _, value := range resp.Header.Values("Warning") {
+		text, ok := legacyWarning(value)
+		if !ok {
+			continue
+		}
+
+		d := Deprecation{
+			URL:     u.String(),
+			Warning: text,
+			Header:  resp.Header,
+		}
+
+		// a malformed Link header is reported when looking for the next link
+		if alternate, ok, _ := findLink(resp.Header, "alternate"); ok {
+			if alternateURL, err := u.Parse(alternate); err == nil {
+				d.Alternate = alternateURL.String()
+			}
+		}
+
+		return d, true
+	}
+
+	return Deprecation{}, false
+}
+
+// legacyWarning returns the text of a Warning header value, such as `299 - "Deprecated"`, if its code is 299.
+func legacyWarning(value string) (string, bool) {
+	code, rest, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok || code != "299" {
+		return "", false
+	}
+
+	_, text, ok := strings.Cut(rest, `"`)
+	if !ok {
+		return "", false
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"':
+			return b.String(), true
+
+		case '\\':
+			if i++; i < len(text) {
+				b.WriteByte(text[i])
+			}
+
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+
+	return "", false
+}
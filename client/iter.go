@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+)
+
+// All returns an iterator over every item of a paginated resource, across all of its pages.
+// The items of each page are read from the response by decode.
+//
+//	for item, err := range client.All(ctx, http.DefaultClient, "https://example.com/items", client.DecodeJSON[Item]) {
+//		if err != nil {
+//			// handle err
+//		}
+//	}
+//
+// If an error occurs, it is yielded once with the zero value of T and iteration stops.
+// Breaking out of the loop early stops any further pages being requested.
+func All[T any](ctx context.Context, c *http.Client, startURL string, decode func(*http.Response) ([]T, error), opts ...Option) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		it := NewIterator(c, startURL, opts...)
+		defer it.Close()
+
+		for it.Next(ctx) {
+			items, err := decode(it.Response())
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+
+		if err := it.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// DecodeJSON decodes a response body holding a JSON array of items, for use with All.
+func DecodeJSON[T any](resp *http.Response) ([]T, error) {
+	var items []T
+	err := json.NewDecoder(resp.Body).Decode(&items)
+
+	return items, err
+}
@@ -0,0 +1,18 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/JoeReid/pagination"
+)
+
+// findLink returns the target of the first link with the given relation in the Link headers, if there is one.
+func findLink(header http.Header, rel string) (string, bool, error) {
+	links, err := pagination.ParseLinks(header.Values("Link")...)
+	if err != nil {
+		return "", false, err
+	}
+
+	link, ok := pagination.FindLink(links, rel)
+	return link.URL, ok, nil
+}
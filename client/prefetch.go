@@ -0,0 +1,115 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// WithPrefetch requests up to n pages ahead of the current one in a background goroutine, while the
+// caller processes the current page. Prefetched pages are read into memory, so n bounds the memory used.
+//
+// Pages and errors are still returned by Next in order. Requests made in the background use the context
+// given to the first call to Next, and are cancelled by Close. Functions given to WithDeprecationHandler
+// are called from the background goroutine.
+func WithPrefetch(n int) Option {
+	return func(it *Iterator) {
+		it.prefetch = n
+	}
+}
+
+type page struct {
+	resp *http.Response
+	err  error
+}
+
+func (it *Iterator) nextPrefetched(ctx context.Context) bool {
+	if it.pages == nil {
+		it.startPrefetch(ctx)
+	}
+
+	// prefer reporting cancellation over any pages already buffered
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+
+	select {
+	case p, ok := <-it.pages:
+		if !ok {
+			it.stopPrefetch()
+			return false
+		}
+
+		it.resp, it.err = p.resp, p.err
+		return p.resp != nil
+
+	case <-ctx.Done():
+		it.err = ctx.Err()
+		return false
+	}
+}
+
+func (it *Iterator) startPrefetch(ctx context.Context) {
+	ctx, it.cancel = context.WithCancel(ctx)
+
+	// the goroutine holds one page while waiting to send it, so the buffer holds one fewer
+	it.pages = make(chan page, it.prefetch-1)
+
+	go it.run(ctx, it.next, it.pages)
+	it.next = ""
+}
+
+func (it *Iterator) run(ctx context.Context, next string, pages chan<- page) {
+	defer close(pages)
+
+	for next != "" {
+		resp, nextURL, err := it.fetch(ctx, next)
+		if resp != nil {
+			if body, readErr := buffer(resp); readErr != nil {
+				resp, err = nil, readErr
+			} else {
+				resp.Body = body
+			}
+		}
+
+		select {
+		case pages <- page{resp: resp, err: err}:
+		case <-ctx.Done():
+			return
+		}
+
+		if err != nil {
+			return
+		}
+
+		next = nextURL
+	}
+}
+
+// stopPrefetch cancels any background requests, and waits for the goroutine making them to exit.
+func (it *Iterator) stopPrefetch() {
+	if it.pages == nil {
+		return
+	}
+
+	it.cancel()
+	for range it.pages {
+		// prefetched bodies are held in memory, so need not be closed
+	}
+
+	it.pages = nil
+}
+
+// buffer reads the body of the response into memory, so its connection is freed for the next request.
+func buffer(resp *http.Response) (io.ReadCloser, error) {
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
@@ -0,0 +1,106 @@
+package pagination
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrNotInteger is returned when the maxItems parameter is not a whole number.
+	ErrNotInteger = errors.New("pagination: must be a whole number")
+
+	// ErrBelowMinimum is returned when the maxItems parameter is less than the minimum allowed by the middleware.
+	ErrBelowMinimum = errors.New("pagination: below the minimum")
+)
+
+// ParamError describes a pagination query parameter that was rejected by the middleware.
+type ParamError struct {
+	Param string
+	Value string
+	Err   error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("pagination: invalid %s parameter %q: %s", e.Param, e.Value, strings.TrimPrefix(e.Err.Error(), "pagination: "))
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// LegacyError is returned when a request uses a legacy pagination method after its cutoff, see WithLegacyCutoff.
+type LegacyError struct {
+	Scheme    string
+	Status    int
+	Alternate url.URL
+}
+
+func (e *LegacyError) Error() string {
+	return "pagination: legacy pagination method is no longer supported, use " + e.Alternate.String()
+}
+
+// ErrorHandler writes the response to a request that was rejected by the middleware,
+// such as one with an invalid maxItems parameter or an expired page token.
+//
+// Errors describing an invalid parameter are of type *ParamError, and those rejecting
+// a legacy pagination method are of type *LegacyError.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// ProblemErrorHandler is the default ErrorHandler. It writes an RFC 9457 application/problem+json response,
+// listing the invalid parameter under the "invalid-params" extension member.
+//
+// Requests using a legacy pagination method after its cutoff are given the equivalent URL, in the
+// specification's format, as a rel="alternate" link and under the "alternate" extension member.
+func ProblemErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	p := problem{
+		Type:   "about:blank",
+		Status: http.StatusInternalServerError,
+	}
+
+	var paramErr *ParamError
+	if errors.As(err, &paramErr) {
+		p.Status = http.StatusBadRequest
+		p.Detail = "The " + paramErr.Param + " parameter is invalid."
+		p.InvalidParams = []invalidParam{{
+			Name:   paramErr.Param,
+			Reason: strings.TrimPrefix(paramErr.Err.Error(), "pagination: "),
+		}}
+	}
+
+	var legacyErr *LegacyError
+	if errors.As(err, &legacyErr) {
+		p.Status = legacyErr.Status
+		if p.Status < 400 || p.Status > 599 {
+			p.Status = http.StatusGone
+		}
+
+		p.Detail = "This pagination method is no longer supported. Please use alternate method."
+		p.Alternate = legacyErr.Alternate.String()
+
+		w.Header().Add("Link", Link{URL: p.Alternate, Rel: []string{"alternate"}}.String())
+	}
+
+	p.Title = http.StatusText(p.Status)
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+type problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	InvalidParams []invalidParam `json:"invalid-params,omitempty"`
+	Alternate     string         `json:"alternate,omitempty"`
+}
+
+type invalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
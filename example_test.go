@@ -60,6 +60,29 @@ func ExampleMiddleware_backwardsCompatible() {
 	// response: [<https://example.com/items?maxItems=10&page=abc>; rel="alternate" <https://example.com/items?maxItems=10&page=test>; rel="next"]
 }
 
+func ExampleOffsetLimitRewriter() {
+	middleware := pagination.NewMiddleware(
+		pagination.WithBackwardsCompatibility(pagination.OffsetLimitRewriter(pagination.DefaultParams)),
+	)
+
+	r := chi.NewRouter()
+	r.With(middleware).Get("/items", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := pagination.Offset(r)
+		fmt.Printf("request: maxItems=%d offset=%d\n", pagination.MaxItems(r), offset)
+
+		pagination.SetNext(r, pagination.OffsetToken(offset+pagination.MaxItems(r)))
+		w.Write([]byte("...Data..."))
+	})
+
+	// Simulate a request to the handler and print the response headers
+	// real applications should use http.ListenAndServe or similar
+	simulateRequest(r, "https://example.com/items?offset=20&limit=10")
+
+	//Output:
+	// request: maxItems=10 offset=20
+	// response: [<https://example.com/items?maxItems=10&page=eyJvZmZzZXQiOjIwfQ>; rel="alternate" <https://example.com/items?maxItems=10&page=eyJvZmZzZXQiOjMwfQ>; rel="next"]
+}
+
 func shim(legacy url.URL) (newURL url.URL, updated bool) {
 	newURL = legacy
 
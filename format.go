@@ -0,0 +1,438 @@
+package pagination
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Format serializes page cursors to and from bytes, before they are encoded as URL safe tokens.
+type Format interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSON is the default format, as used by EncodeToken and DecodeToken.
+	JSON Format = jsonFormat{}
+
+	// Binary is a compact format, which writes the fields of a cursor in order without any names or framing.
+	//
+	// It supports booleans, numbers, strings, byte slices, slices, arrays, pointers, structs and any type
+	// implementing encoding.BinaryMarshaler and encoding.BinaryUnmarshaler (such as time.Time). As field
+	// names are not recorded, fields must not be reordered once tokens have been issued.
+	Binary Format = binaryFormat{}
+)
+
+// Compressed wraps a format, compressing its output with DEFLATE when doing so makes it smaller.
+//
+// Small cursors rarely benefit from compression, so this is most useful with large or repetitive cursors.
+func Compressed(f Format) Format {
+	return compressedFormat{inner: f}
+}
+
+// Versioned wraps a format, prefixing its output with the schema version of the cursor.
+//
+// When a token of an older version is unmarshalled, it is first upgraded to the current version by the
+// migrations, which must cover every version still held by clients. This allows the shape of a cursor
+// to change without breaking clients part way through paginating.
+func Versioned(f Format, version int, migrations ...Migration) Format {
+	rtn := versionedFormat{
+		inner:      f,
+		version:    version,
+		migrations: make(map[int]Migration, len(migrations)),
+	}
+
+	for _, m := range migrations {
+		rtn.migrations[m.from] = m
+	}
+
+	return rtn
+}
+
+// Migration upgrades a cursor from one schema version to the next. See Migrate.
+type Migration struct {
+	from    int
+	migrate func(data []byte, f Format) ([]byte, error)
+}
+
+// Migrate returns a Migration that upgrades cursors of the given version, of type From,
+// to cursors of the next version, of type To.
+func Migrate[From, To any](from int, fn func(From) (To, error)) Migration {
+	return Migration{
+		from: from,
+		migrate: func(data []byte, f Format) ([]byte, error) {
+			var old From
+			if err := f.Unmarshal(data, &old); err != nil {
+				return nil, err
+			}
+
+			upgraded, err := fn(old)
+			if err != nil {
+				return nil, err
+			}
+
+			return f.Marshal(upgraded)
+		},
+	}
+}
+
+type versionedFormat struct {
+	inner      Format
+	version    int
+	migrations map[int]Migration
+}
+
+func (f versionedFormat) Marshal(v interface{}) ([]byte, error) {
+	b, err := f.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(binary.AppendUvarint(nil, uint64(f.version)), b...), nil
+}
+
+func (f versionedFormat) Unmarshal(data []byte, v interface{}) error {
+	u, n := binary.Uvarint(data)
+	if n <= 0 || u > uint64(f.version) {
+		return fmt.Errorf("%w: unknown token version", ErrInvalidToken)
+	}
+
+	data = data[n:]
+
+	for version := int(u); version < f.version; version++ {
+		m, ok := f.migrations[version]
+		if !ok {
+			return fmt.Errorf("%w: no migration from token version %d", ErrInvalidToken, version)
+		}
+
+		var err error
+		if data, err = m.migrate(data, f.inner); err != nil {
+			return fmt.Errorf("migrating token from version %d: %w", version, err)
+		}
+	}
+
+	return f.inner.Unmarshal(data, v)
+}
+
+type jsonFormat struct{}
+
+func (jsonFormat) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonFormat) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// maxDecompressedSize bounds the size of decompressed tokens, as they are provided by clients.
+const maxDecompressedSize = 64 << 10
+
+const (
+	uncompressed byte = iota
+	deflated
+)
+
+type compressedFormat struct {
+	inner Format
+}
+
+func (f compressedFormat) Marshal(v interface{}) ([]byte, error) {
+	b, err := f.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer([]byte{deflated})
+
+	// flate only returns errors from the underlying writer, which is a bytes.Buffer
+	w, _ := flate.NewWriter(buf, flate.BestCompression)
+	w.Write(b)
+	w.Close()
+
+	if buf.Len() >= len(b)+1 {
+		return append([]byte{uncompressed}, b...), nil
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (f compressedFormat) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return ErrInvalidToken
+	}
+
+	switch data[0] {
+	case uncompressed:
+		return f.inner.Unmarshal(data[1:], v)
+
+	case deflated:
+		b, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data[1:])), maxDecompressedSize+1))
+		if err != nil || len(b) > maxDecompressedSize {
+			return ErrInvalidToken
+		}
+
+		return f.inner.Unmarshal(b, v)
+
+	default:
+		return ErrInvalidToken
+	}
+}
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+type binaryFormat struct{}
+
+func (binaryFormat) Marshal(v interface{}) ([]byte, error) {
+	// pointers are followed at the top level, so that marshalling a cursor or a pointer to it is equivalent
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, fmt.Errorf("pagination: cannot marshal nil %T", v)
+	}
+
+	return appendBinary(nil, rv)
+}
+
+func (binaryFormat) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("pagination: cannot unmarshal into non-pointer %T", v)
+	}
+
+	rest, err := readBinary(data, rv.Elem())
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 0 {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// isBinaryMarshaler reports whether values of the type should be written with their own binary encoding.
+func isBinaryMarshaler(t reflect.Type) bool {
+	return t.Kind() != reflect.Pointer && t.Implements(binaryMarshalerType) && reflect.PointerTo(t).Implements(binaryUnmarshalerType)
+}
+
+func appendBinary(b []byte, v reflect.Value) ([]byte, error) {
+	if isBinaryMarshaler(v.Type()) {
+		data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		return append(binary.AppendUvarint(b, uint64(len(data))), data...), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.AppendVarint(b, v.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.AppendUvarint(b, v.Uint()), nil
+
+	case reflect.Float32, reflect.Float64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.Float())), nil
+
+	case reflect.String:
+		return append(binary.AppendUvarint(b, uint64(v.Len())), v.String()...), nil
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return append(binary.AppendUvarint(b, uint64(v.Len())), v.Bytes()...), nil
+		}
+
+		b = binary.AppendUvarint(b, uint64(v.Len()))
+		return appendBinaryElems(b, v)
+
+	case reflect.Array:
+		return appendBinaryElems(b, v)
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			return append(b, 0), nil
+		}
+		return appendBinary(append(b, 1), v.Elem())
+
+	case reflect.Struct:
+		var err error
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+
+			if b, err = appendBinary(b, v.Field(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+
+	default:
+		return nil, fmt.Errorf("pagination: binary format does not support %s", v.Type())
+	}
+}
+
+func appendBinaryElems(b []byte, v reflect.Value) ([]byte, error) {
+	var err error
+	for i := 0; i < v.Len(); i++ {
+		if b, err = appendBinary(b, v.Index(i)); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func readBinary(b []byte, v reflect.Value) ([]byte, error) {
+	if isBinaryMarshaler(v.Type()) {
+		data, rest, err := readBinaryBytes(b)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+			return nil, errors.Join(ErrInvalidToken, err)
+		}
+
+		return rest, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if len(b) < 1 || b[0] > 1 {
+			return nil, ErrInvalidToken
+		}
+
+		v.SetBool(b[0] == 1)
+		return b[1:], nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, n := binary.Varint(b)
+		if n <= 0 || v.OverflowInt(i) {
+			return nil, ErrInvalidToken
+		}
+
+		v.SetInt(i)
+		return b[n:], nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, n := binary.Uvarint(b)
+		if n <= 0 || v.OverflowUint(u) {
+			return nil, ErrInvalidToken
+		}
+
+		v.SetUint(u)
+		return b[n:], nil
+
+	case reflect.Float32, reflect.Float64:
+		if len(b) < 8 {
+			return nil, ErrInvalidToken
+		}
+
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		return b[8:], nil
+
+	case reflect.String:
+		data, rest, err := readBinaryBytes(b)
+		if err != nil {
+			return nil, err
+		}
+
+		v.SetString(string(data))
+		return rest, nil
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data, rest, err := readBinaryBytes(b)
+			if err != nil {
+				return nil, err
+			}
+
+			v.SetBytes(bytes.Clone(data))
+			return rest, nil
+		}
+
+		l, n := binary.Uvarint(b)
+		// every element takes at least one byte, which bounds the allocation below
+		if n <= 0 || l > uint64(len(b)-n) {
+			return nil, ErrInvalidToken
+		}
+
+		v.Set(reflect.MakeSlice(v.Type(), int(l), int(l)))
+		return readBinaryElems(b[n:], v)
+
+	case reflect.Array:
+		return readBinaryElems(b, v)
+
+	case reflect.Pointer:
+		if len(b) < 1 || b[0] > 1 {
+			return nil, ErrInvalidToken
+		}
+
+		if b[0] == 0 {
+			v.SetZero()
+			return b[1:], nil
+		}
+
+		v.Set(reflect.New(v.Type().Elem()))
+		return readBinary(b[1:], v.Elem())
+
+	case reflect.Struct:
+		var err error
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+
+			if b, err = readBinary(b, v.Field(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+
+	default:
+		return nil, fmt.Errorf("pagination: binary format does not support %s", v.Type())
+	}
+}
+
+func readBinaryElems(b []byte, v reflect.Value) ([]byte, error) {
+	var err error
+	for i := 0; i < v.Len(); i++ {
+		if b, err = readBinary(b, v.Index(i)); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func readBinaryBytes(b []byte) (data, rest []byte, err error) {
+	l, n := binary.Uvarint(b)
+	if n <= 0 || l > uint64(len(b)-n) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	return b[n : n+int(l)], b[n+int(l):], nil
+}
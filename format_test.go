@@ -0,0 +1,210 @@
+package pagination
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type keysetCursor struct {
+	SortValue  float64
+	Tiebreaker int64
+	CreatedAt  time.Time
+	Filter     []byte
+	Tags       []string
+	Parent     *keysetCursor
+	Active     bool
+	Shard      uint16
+	internal   string
+}
+
+func TestFormats(t *testing.T) {
+	cursor := keysetCursor{
+		SortValue:  12.5,
+		Tiebreaker: -42,
+		CreatedAt:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Filter:     []byte{0xde, 0xad, 0xbe, 0xef},
+		Tags:       []string{"open", "urgent"},
+		Parent:     &keysetCursor{Tiebreaker: 7},
+		Active:     true,
+		Shard:      3,
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+	}{
+		{name: "json", format: JSON},
+		{name: "binary", format: Binary},
+		{name: "compressed json", format: Compressed(JSON)},
+		{name: "compressed binary", format: Compressed(Binary)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.format.Marshal(cursor)
+			require.NoError(t, err)
+
+			var value keysetCursor
+			require.NoError(t, tt.format.Unmarshal(b, &value))
+
+			assert.Equal(t, cursor.SortValue, value.SortValue)
+			assert.Equal(t, cursor.Tiebreaker, value.Tiebreaker)
+			assert.True(t, cursor.CreatedAt.Equal(value.CreatedAt))
+			assert.Equal(t, cursor.Filter, value.Filter)
+			assert.Equal(t, cursor.Tags, value.Tags)
+			assert.Equal(t, cursor.Parent.Tiebreaker, value.Parent.Tiebreaker)
+			assert.Nil(t, value.Parent.Parent)
+			assert.Equal(t, cursor.Active, value.Active)
+			assert.Equal(t, cursor.Shard, value.Shard)
+		})
+	}
+}
+
+func TestBinaryIsCompact(t *testing.T) {
+	cursor := testCursor{ID: "abc", Offset: 10}
+
+	jsonBytes, err := JSON.Marshal(cursor)
+	require.NoError(t, err)
+
+	binaryBytes, err := Binary.Marshal(cursor)
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte{3, 'a', 'b', 'c', 20}, binaryBytes)
+	assert.Less(t, len(binaryBytes), len(jsonBytes))
+
+	pointerBytes, err := Binary.Marshal(&cursor)
+	require.NoError(t, err)
+	assert.Equal(t, binaryBytes, pointerBytes)
+}
+
+func TestCompressed(t *testing.T) {
+	small, err := Compressed(JSON).Marshal(testCursor{ID: "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, uncompressed, small[0])
+
+	large, err := Compressed(JSON).Marshal(testCursor{ID: strings.Repeat("abc", 100)})
+	require.NoError(t, err)
+	assert.Equal(t, deflated, large[0])
+	assert.Less(t, len(large), 300)
+}
+
+func TestBinaryMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: []byte{}},
+		{name: "truncated string", data: []byte{10, 'a'}},
+		{name: "missing offset", data: []byte{3, 'a', 'b', 'c'}},
+		{name: "trailing data", data: []byte{3, 'a', 'b', 'c', 20, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value testCursor
+			assert.ErrorIs(t, Binary.Unmarshal(tt.data, &value), ErrInvalidToken)
+		})
+	}
+}
+
+func TestBinaryUnsupported(t *testing.T) {
+	_, err := Binary.Marshal(map[string]string{"a": "b"})
+	assert.Error(t, err)
+
+	_, err = Binary.Marshal(nil)
+	assert.Error(t, err)
+
+	assert.Error(t, Binary.Unmarshal([]byte{0}, testCursor{}))
+}
+
+type cursorV1 struct {
+	Offset int `json:"offset"`
+}
+
+type cursorV2 struct {
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+}
+
+type cursorV3 struct {
+	Offset int      `json:"offset"`
+	Sort   []string `json:"sort"`
+}
+
+func TestVersioned(t *testing.T) {
+	migrations := []Migration{
+		Migrate(1, func(c cursorV1) (cursorV2, error) {
+			return cursorV2{Offset: c.Offset, Sort: "id"}, nil
+		}),
+		Migrate(2, func(c cursorV2) (cursorV3, error) {
+			return cursorV3{Offset: c.Offset, Sort: []string{c.Sort}}, nil
+		}),
+	}
+
+	tests := []struct {
+		name         string
+		encodeFormat Format
+		encodeValue  interface{}
+		decodeFormat Format
+		expectValue  cursorV3
+		expectErr    error
+	}{
+		{
+			name:         "current version",
+			encodeFormat: Versioned(JSON, 3),
+			encodeValue:  cursorV3{Offset: 10, Sort: []string{"name"}},
+			decodeFormat: Versioned(JSON, 3, migrations...),
+			expectValue:  cursorV3{Offset: 10, Sort: []string{"name"}},
+		},
+		{
+			name:         "previous version",
+			encodeFormat: Versioned(JSON, 2),
+			encodeValue:  cursorV2{Offset: 10, Sort: "name"},
+			decodeFormat: Versioned(JSON, 3, migrations...),
+			expectValue:  cursorV3{Offset: 10, Sort: []string{"name"}},
+		},
+		{
+			name:         "first version",
+			encodeFormat: Versioned(Binary, 1),
+			encodeValue:  cursorV1{Offset: 10},
+			decodeFormat: Versioned(Binary, 3, migrations...),
+			expectValue:  cursorV3{Offset: 10, Sort: []string{"id"}},
+		},
+		{
+			name:         "missing migration",
+			encodeFormat: Versioned(JSON, 1),
+			encodeValue:  cursorV1{Offset: 10},
+			decodeFormat: Versioned(JSON, 3, migrations[1]),
+			expectErr:    ErrInvalidToken,
+		},
+		{
+			name:         "future version",
+			encodeFormat: Versioned(JSON, 4),
+			encodeValue:  cursorV3{Offset: 10},
+			decodeFormat: Versioned(JSON, 3, migrations...),
+			expectErr:    ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.encodeFormat.Marshal(tt.encodeValue)
+			require.NoError(t, err)
+
+			var value cursorV3
+
+			err = tt.decodeFormat.Unmarshal(b, &value)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectValue, value)
+		})
+	}
+}
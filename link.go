@@ -0,0 +1,325 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidLink is returned when a Link header can't be parsed.
+var ErrInvalidLink = errors.New("pagination: invalid link header")
+
+// Link is a web link, as carried by the Link header defined in RFC 8288.
+type Link struct {
+	URL string
+
+	// Rel holds the relation types of the link, such as "next". They are compared case insensitively,
+	// so are lower cased when parsed.
+	Rel []string
+
+	Title    string
+	Type     string
+	Hreflang string
+
+	// Params holds any other target attributes, keyed by their lower cased name.
+	Params map[string]string
+}
+
+// HasRel reports whether the link has the given relation type.
+func (l Link) HasRel(rel string) bool {
+	for _, r := range l.Rel {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String formats the link as a Link header value.
+//
+// Titles that are not ASCII are written as an RFC 8187 title* parameter.
+func (l Link) String() string {
+	var b strings.Builder
+
+	b.WriteString("<" + l.URL + ">")
+
+	if len(l.Rel) > 0 {
+		b.WriteString("; rel=" + quote(strings.Join(l.Rel, " ")))
+	}
+
+	if l.Title != "" {
+		if isASCII(l.Title) {
+			b.WriteString("; title=" + quote(l.Title))
+		} else {
+			b.WriteString("; title*=UTF-8''" + strings.ReplaceAll(url.QueryEscape(l.Title), "+", "%20"))
+		}
+	}
+
+	if l.Type != "" {
+		b.WriteString("; type=" + quote(l.Type))
+	}
+
+	if l.Hreflang != "" {
+		b.WriteString("; hreflang=" + l.Hreflang)
+	}
+
+	names := make([]string, 0, len(l.Params))
+	for name := range l.Params {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		b.WriteString("; " + name + "=" + quote(l.Params[name]))
+	}
+
+	return b.String()
+}
+
+// FindLink returns the first link with the given relation type.
+func FindLink(links []Link, rel string) (Link, bool) {
+	for _, l := range links {
+		if l.HasRel(rel) {
+			return l, true
+		}
+	}
+
+	return Link{}, false
+}
+
+// ParseLinks parses the links from one or more Link header values, each of which may hold several
+// comma separated links.
+func ParseLinks(values ...string) ([]Link, error) {
+	var links []Link
+
+	for _, value := range values {
+		p := linkParser{s: value}
+
+		for {
+			p.skipSpace()
+			if p.done() {
+				break
+			}
+
+			l, err := p.link()
+			if err != nil {
+				return nil, err
+			}
+
+			links = append(links, l)
+
+			p.skipSpace()
+			if p.done() {
+				break
+			}
+
+			if !p.consume(',') {
+				return nil, p.errorf("expected ','")
+			}
+		}
+	}
+
+	return links, nil
+}
+
+type linkParser struct {
+	s   string
+	pos int
+}
+
+func (p *linkParser) link() (Link, error) {
+	if !p.consume('<') {
+		return Link{}, p.errorf("expected '<'")
+	}
+
+	end := strings.IndexByte(p.s[p.pos:], '>')
+	if end < 0 {
+		return Link{}, p.errorf("unterminated URL")
+	}
+
+	l := Link{URL: p.s[p.pos : p.pos+end]}
+	p.pos += end + 1
+
+	seen := make(map[string]bool)
+
+	for {
+		p.skipSpace()
+		if !p.consume(';') {
+			return l, nil
+		}
+
+		p.skipSpace()
+		name := strings.ToLower(p.token())
+		if name == "" {
+			return Link{}, p.errorf("expected parameter name")
+		}
+
+		var value string
+
+		p.skipSpace()
+		if p.consume('=') {
+			p.skipSpace()
+
+			var err error
+			if value, err = p.value(); err != nil {
+				return Link{}, err
+			}
+		}
+
+		// RFC 8288 says only the first occurrence of these parameters is used
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+
+		switch name {
+		case "rel":
+			l.Rel = strings.Fields(strings.ToLower(value))
+
+		case "title":
+			// title* takes precedence when both are given
+			if !seen["title*"] {
+				l.Title = value
+			}
+
+		case "title*":
+			title, err := decodeExtValue(value)
+			if err != nil {
+				return Link{}, p.errorf("%v", err)
+			}
+
+			l.Title = title
+
+		case "type":
+			l.Type = value
+
+		case "hreflang":
+			l.Hreflang = value
+
+		default:
+			if l.Params == nil {
+				l.Params = make(map[string]string)
+			}
+
+			l.Params[name] = value
+		}
+	}
+}
+
+func (p *linkParser) value() (string, error) {
+	if !p.consume('"') {
+		return p.token(), nil
+	}
+
+	var b strings.Builder
+
+	for !p.done() {
+		c := p.s[p.pos]
+		p.pos++
+
+		switch c {
+		case '"':
+			return b.String(), nil
+
+		case '\\':
+			if p.done() {
+				return "", p.errorf("unterminated quoted string")
+			}
+
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("unterminated quoted string")
+}
+
+// token reads an RFC 9110 token, which may be empty.
+func (p *linkParser) token() string {
+	start := p.pos
+	for !p.done() && isTokenChar(p.s[p.pos]) {
+		p.pos++
+	}
+
+	return p.s[start:p.pos]
+}
+
+func (p *linkParser) skipSpace() {
+	for !p.done() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *linkParser) consume(c byte) bool {
+	if p.done() || p.s[p.pos] != c {
+		return false
+	}
+
+	p.pos++
+	return true
+}
+
+func (p *linkParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *linkParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrInvalidLink, fmt.Sprintf(format, args...), p.pos)
+}
+
+// decodeExtValue decodes an RFC 8187 ext-value, which must use the UTF-8 charset.
+func decodeExtValue(value string) (string, error) {
+	charset, rest, ok := strings.Cut(value, "'")
+	if !ok {
+		return "", errors.New("malformed extended value")
+	}
+
+	_, encoded, ok := strings.Cut(rest, "'")
+	if !ok {
+		return "", errors.New("malformed extended value")
+	}
+
+	if !strings.EqualFold(charset, "UTF-8") {
+		return "", fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	decoded, err := url.PathUnescape(encoded)
+	if err != nil || !utf8.ValidString(decoded) {
+		return "", errors.New("malformed extended value")
+	}
+
+	return decoded, nil
+}
+
+// quote returns the value as an RFC 9110 quoted-string.
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func isTokenChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	default:
+		return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
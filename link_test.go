@@ -0,0 +1,169 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLinks(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []string
+		expectLinks []Link
+		expectErr   bool
+	}{
+		{
+			name:        "empty",
+			values:      []string{""},
+			expectLinks: nil,
+		},
+		{
+			name:        "single",
+			values:      []string{`<https://example.com/items?page=abc>; rel="next"`},
+			expectLinks: []Link{{URL: "https://example.com/items?page=abc", Rel: []string{"next"}}},
+		},
+		{
+			name:   "several per header",
+			values: []string{`<https://example.com/a,b>; rel="prev"; title="a, b", <https://example.com/c>; rel=next`},
+			expectLinks: []Link{
+				{URL: "https://example.com/a,b", Rel: []string{"prev"}, Title: "a, b"},
+				{URL: "https://example.com/c", Rel: []string{"next"}},
+			},
+		},
+		{
+			name:   "several headers",
+			values: []string{`</a>; rel="prev"`, `</b>; rel="next"`},
+			expectLinks: []Link{
+				{URL: "/a", Rel: []string{"prev"}},
+				{URL: "/b", Rel: []string{"next"}},
+			},
+		},
+		{
+			name:        "multiple rel values",
+			values:      []string{`</a>; REL="Last  Next"`},
+			expectLinks: []Link{{URL: "/a", Rel: []string{"last", "next"}}},
+		},
+		{
+			name:   "target attributes",
+			values: []string{`</a> ; rel=alternate ; type="application/json"; hreflang=en-GB; title="say \"hi\""; media=screen`},
+			expectLinks: []Link{{
+				URL:      "/a",
+				Rel:      []string{"alternate"},
+				Title:    `say "hi"`,
+				Type:     "application/json",
+				Hreflang: "en-GB",
+				Params:   map[string]string{"media": "screen"},
+			}},
+		},
+		{
+			name:        "extended title",
+			values:      []string{`</a>; title="euro rates"; title*=UTF-8'en'%e2%82%ac%20rates`},
+			expectLinks: []Link{{URL: "/a", Title: "€ rates"}},
+		},
+		{
+			name:        "first rel wins",
+			values:      []string{`</a>; rel="next"; rel="prev"`},
+			expectLinks: []Link{{URL: "/a", Rel: []string{"next"}}},
+		},
+		{
+			name:      "missing angle bracket",
+			values:    []string{`/a; rel="next"`},
+			expectErr: true,
+		},
+		{
+			name:      "unterminated URL",
+			values:    []string{`</a; rel="next"`},
+			expectErr: true,
+		},
+		{
+			name:      "unterminated quoted string",
+			values:    []string{`</a>; rel="next`},
+			expectErr: true,
+		},
+		{
+			name:      "missing comma",
+			values:    []string{`</a>; rel="next" </b>`},
+			expectErr: true,
+		},
+		{
+			name:      "unsupported charset",
+			values:    []string{`</a>; title*=ISO-8859-1''%a3`},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := ParseLinks(tt.values...)
+			if tt.expectErr {
+				assert.ErrorIs(t, err, ErrInvalidLink)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectLinks, links)
+		})
+	}
+}
+
+func TestLinkString(t *testing.T) {
+	tests := []struct {
+		name   string
+		link   Link
+		expect string
+	}{
+		{
+			name:   "rel",
+			link:   Link{URL: "https://example.com/items?page=abc", Rel: []string{"next"}},
+			expect: `<https://example.com/items?page=abc>; rel="next"`,
+		},
+		{
+			name:   "multiple rel values",
+			link:   Link{URL: "/a", Rel: []string{"last", "next"}},
+			expect: `</a>; rel="last next"`,
+		},
+		{
+			name: "target attributes",
+			link: Link{
+				URL:      "/a",
+				Rel:      []string{"alternate"},
+				Title:    `say "hi"`,
+				Type:     "application/json",
+				Hreflang: "en-GB",
+				Params:   map[string]string{"media": "screen", "anchor": "#b"},
+			},
+			expect: `</a>; rel="alternate"; title="say \"hi\""; type="application/json"; hreflang=en-GB; anchor="#b"; media="screen"`,
+		},
+		{
+			name:   "extended title",
+			link:   Link{URL: "/a", Title: "€ rates"},
+			expect: `</a>; title*=UTF-8''%E2%82%AC%20rates`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, tt.link.String())
+
+			links, err := ParseLinks(tt.link.String())
+			require.NoError(t, err)
+			assert.Equal(t, []Link{tt.link}, links)
+		})
+	}
+}
+
+func TestFindLink(t *testing.T) {
+	links := []Link{
+		{URL: "/a", Rel: []string{"prev"}},
+		{URL: "/b", Rel: []string{"last", "next"}},
+	}
+
+	link, ok := FindLink(links, "NEXT")
+	assert.True(t, ok)
+	assert.Equal(t, "/b", link.URL)
+
+	_, ok = FindLink(links, "first")
+	assert.False(t, ok)
+}
@@ -2,8 +2,11 @@ package pagination
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 )
 
 type Middleware func(http.Handler) http.Handler
@@ -12,7 +15,14 @@ func NewMiddleware(opts ...MiddlewareOpt) Middleware {
 	m := &middleware{
 		maxItemsDefault: 100,
 		maxItemsLimit:   100,
-		rewriter:        func(u url.URL) (url.URL, bool) { return u, false },
+		maxItemsMinimum: 1,
+		params:          DefaultParams,
+		format:          JSON,
+		errorHandler:    ProblemErrorHandler,
+		legacyHeaders:   make(http.Header),
+		warning:         func(*http.Request) string { return defaultWarning },
+		legacyHook:      func(LegacyUsage) {},
+		responders:      make(map[string]LegacyResponder),
 	}
 
 	for _, opt := range opts {
@@ -36,9 +46,196 @@ func WithMaxItemsLimit(maxItems int) MiddlewareOpt {
 	}
 }
 
+// WithMaxItemsMinimum sets the smallest value of maxItems a client may request.
+// Requests for fewer items are rejected, see WithErrorHandler. The default minimum is 1.
+func WithMaxItemsMinimum(maxItems int) MiddlewareOpt {
+	return func(m *middleware) {
+		m.maxItemsMinimum = maxItems
+	}
+}
+
+// WithParams renames the query parameters used for pagination, in place of DefaultParams.
+//
+// This is intended for APIs already bound to other names, and applies to links, MaxItems and Page alike.
+// Empty names are left at their default.
+func WithParams(p Params) MiddlewareOpt {
+	return func(m *middleware) {
+		if p.MaxItems != "" {
+			m.params.MaxItems = p.MaxItems
+		}
+
+		if p.Page != "" {
+			m.params.Page = p.Page
+		}
+	}
+}
+
+// WithBackwardsCompatibility adds a Rewriter that translates requests using a legacy pagination method.
+//
+// It may be given more than once, for APIs that have gone through several pagination methods.
+// Rewriters are tried in the order given, and the first to rewrite a request is used.
 func WithBackwardsCompatibility(shim Rewriter) MiddlewareOpt {
+	return WithLegacyScheme("", shim)
+}
+
+// WithLegacyScheme adds a named Rewriter, in the same manner as WithBackwardsCompatibility.
+//
+// The name of the scheme used to rewrite a request is available to handlers through LegacyScheme.
+func WithLegacyScheme(name string, shim Rewriter) MiddlewareOpt {
+	return func(m *middleware) {
+		m.schemes = append(m.schemes, legacyScheme{name: name, rewriter: shim})
+	}
+}
+
+// WithTokenCodec protects page tokens with the given codec.
+//
+// Page tokens set by handlers are sealed by the codec before being written to link headers,
+// and page tokens sent by clients are verified before the request reaches the handler.
+// Requests with an invalid or expired page token are rejected, see WithErrorHandler.
+//
+// On requests rewritten by a legacy scheme, page tokens produced by OffsetToken, as the built-in rewriters
+// do, are trusted unless the client sent them. Any other page token is verified, including one a Rewriter
+// copies from another parameter, so the Rewriter must not produce page tokens of its own.
+//
+// Handlers continue to see the page tokens they set, via Page.
+func WithTokenCodec(codec *TokenCodec) MiddlewareOpt {
+	return func(m *middleware) {
+		m.codec = codec
+	}
+}
+
+// WithQueryBinding binds page tokens to the query parameters of the request they were issued for,
+// other than maxItems and page, so they can't be replayed against a request for different data.
+//
+// Binding requires the tokens to be protected by WithTokenCodec, and has no effect otherwise.
+func WithQueryBinding() MiddlewareOpt {
+	return func(m *middleware) {
+		m.bindQuery = true
+	}
+}
+
+// WithPathBinding binds page tokens to the path of the request they were issued for,
+// so the same token can't be used against a different endpoint.
+//
+// Binding requires the tokens to be protected by WithTokenCodec, and has no effect otherwise.
+func WithPathBinding() MiddlewareOpt {
+	return func(m *middleware) {
+		m.bindPath = true
+	}
+}
+
+// WithPrincipalBinding binds page tokens to the principal the request was made by, such as a user or tenant ID,
+// as returned by the given function. Tokens issued to one principal are rejected if used by another.
+//
+// Binding requires the tokens to be protected by WithTokenCodec, and has no effect otherwise.
+func WithPrincipalBinding(principal func(*http.Request) string) MiddlewareOpt {
+	return func(m *middleware) {
+		m.principal = principal
+	}
+}
+
+// WithTokenFormat sets the format used by Encode and Decode to serialize page tokens, in place of the default JSON.
+func WithTokenFormat(f Format) MiddlewareOpt {
+	return func(m *middleware) {
+		m.format = f
+	}
+}
+
+// WithErrorHandler sets the handler used to respond to requests rejected by the middleware,
+// in place of ProblemErrorHandler.
+func WithErrorHandler(h ErrorHandler) MiddlewareOpt {
+	return func(m *middleware) {
+		m.errorHandler = h
+	}
+}
+
+// LegacyUsage describes a request that was rewritten by a legacy scheme.
+type LegacyUsage struct {
+	Request   *http.Request
+	Scheme    string
+	Original  url.URL
+	Rewritten url.URL
+}
+
+// WithLegacyHook registers a function that is called for every request rewritten by a legacy scheme,
+// before it is validated or served.
+//
+// This can be used to log or count the use of legacy pagination methods, per client or endpoint,
+// to decide when they can be removed.
+func WithLegacyHook(hook func(LegacyUsage)) MiddlewareOpt {
+	return func(m *middleware) {
+		m.legacyHook = hook
+	}
+}
+
+// LegacyResponse describes the response to a request that was rewritten by a legacy scheme.
+type LegacyResponse struct {
+	Request *http.Request
+	Scheme  string
+	Header  http.Header
+
+	// Links holds the links set by the handler, keyed by relation, with the page tokens set by the handler.
+	// Links removed from the map are not written to the response in the specification's format.
+	Links map[string]url.URL
+}
+
+// LegacyResponder adjusts the response to a request rewritten by a legacy scheme, so that it is
+// in the shape expected by legacy clients. It is called just before the response headers are written.
+type LegacyResponder func(LegacyResponse)
+
+// WithLegacyResponder registers a LegacyResponder for requests rewritten by the named scheme,
+// or by WithBackwardsCompatibility if the scheme is empty.
+//
+// Regardless of the responder, the rel="alternate" link required by the specification is always added.
+func WithLegacyResponder(scheme string, responder LegacyResponder) MiddlewareOpt {
 	return func(m *middleware) {
-		m.rewriter = shim
+		m.responders[scheme] = responder
+	}
+}
+
+// WithDeprecation adds a Deprecation header (RFC 9745) to responses to requests rewritten by a legacy scheme,
+// stating the date the legacy pagination method was deprecated.
+//
+// If doc is not empty, it is also linked as rel="deprecation", and should describe how to migrate.
+func WithDeprecation(date time.Time, doc string) MiddlewareOpt {
+	return func(m *middleware) {
+		m.legacyHeaders.Set("Deprecation", "@"+strconv.FormatInt(date.Unix(), 10))
+
+		if doc != "" {
+			m.legacyHeaders.Add("Link", Link{URL: doc, Rel: []string{"deprecation"}}.String())
+		}
+	}
+}
+
+// WithSunset adds a Sunset header (RFC 8594) to responses to requests rewritten by a legacy scheme,
+// stating the date the legacy pagination method will stop working.
+//
+// If doc is not empty, it is also linked as rel="sunset", and should describe the sunset policy.
+func WithSunset(date time.Time, doc string) MiddlewareOpt {
+	return func(m *middleware) {
+		m.legacyHeaders.Set("Sunset", date.UTC().Format(http.TimeFormat))
+
+		if doc != "" {
+			m.legacyHeaders.Add("Link", Link{URL: doc, Rel: []string{"sunset"}}.String())
+		}
+	}
+}
+
+// WithLegacyCutoff stops serving requests rewritten by a legacy scheme after the cutoff time.
+// Instead, they are rejected with the given status code, typically 410 Gone or 400 Bad Request,
+// and the equivalent URL in the specification's format, see WithErrorHandler.
+// Status codes that are not client errors are replaced with 410 Gone.
+//
+// Middleware is applied per route, so routes that must keep serving legacy requests
+// can use middleware without this option.
+func WithLegacyCutoff(cutoff time.Time, status int) MiddlewareOpt {
+	return func(m *middleware) {
+		m.cutoff = cutoff
+		m.cutoffStatus = status
+
+		if status < 400 || status > 499 {
+			m.cutoffStatus = http.StatusGone
+		}
 	}
 }
 
@@ -48,40 +245,146 @@ func WithBackwardsCompatibility(shim Rewriter) MiddlewareOpt {
 // If the URL is not rewritten, the given URL should be returned and the second return value should be false.
 type Rewriter func(url.URL) (url.URL, bool)
 
+// ChainRewriters returns a Rewriter that tries each of the given rewriters in order,
+// returning the result of the first to rewrite the URL.
+func ChainRewriters(rewriters ...Rewriter) Rewriter {
+	return func(u url.URL) (url.URL, bool) {
+		for _, rewriter := range rewriters {
+			if rewritten, ok := rewriter(u); ok {
+				return rewritten, true
+			}
+		}
+
+		return u, false
+	}
+}
+
+type legacyScheme struct {
+	name     string
+	rewriter Rewriter
+}
+
 type middleware struct {
 	maxItemsDefault int
 	maxItemsLimit   int
-	rewriter        Rewriter
+	maxItemsMinimum int
+	params          Params
+	schemes         []legacyScheme
+	codec           *TokenCodec
+	bindQuery       bool
+	bindPath        bool
+	principal       func(*http.Request) string
+	format          Format
+	errorHandler    ErrorHandler
+	legacyHeaders   http.Header
+	warning         func(*http.Request) string
+	legacyHook      func(LegacyUsage)
+	responders      map[string]LegacyResponder
+	cutoff          time.Time
+	cutoffStatus    int
 }
 
 func (m *middleware) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		reqURL, wasRewritten := m.rewriter(*r.URL)
+		reqURL, scheme, wasRewritten := m.rewrite(*r.URL)
+		if wasRewritten {
+			m.legacyHook(LegacyUsage{Request: r, Scheme: scheme, Original: *r.URL, Rewritten: reqURL})
+		}
+
+		// only offset tokens minted by the built-in rewriters come from the server; any other page token,
+		// or one sent by the client under any parameter, must be opened
+		page, _ := m.params.page(reqURL)
+		_, isOffset := parseOffsetToken(page)
+		fromRewriter := wasRewritten && isOffset && !inQuery(*r.URL, page)
+
+		// legacy requests past the cutoff are rejected before validation, so they are always given the alternate
+		if wasRewritten && !m.cutoff.IsZero() && time.Now().After(m.cutoff) {
+			alternate := reqURL
+			if fromRewriter {
+				alternate = (&state{params: m.params, codec: m.codec, binding: m.binding(r, reqURL)}).sealPage(alternate)
+			}
+
+			m.errorHandler(w, r, &LegacyError{Scheme: scheme, Status: m.cutoffStatus, Alternate: alternate})
+			return
+		}
+
+		current, err := m.enforceRestrictions(reqURL)
+		if err != nil {
+			m.errorHandler(w, r, err)
+			return
+		}
 
 		state := &state{
-			current:      m.enforceRestrictions(reqURL),
-			wasRewritten: wasRewritten,
-			links:        make(map[string]url.URL),
+			current:       current,
+			params:        m.params,
+			wasRewritten:  wasRewritten,
+			scheme:        scheme,
+			legacyHeaders: m.legacyHeaders,
+			responder:     m.responders[scheme],
+			links:         make(map[string]url.URL),
+			codec:         m.codec,
+			binding:       m.binding(r, current),
+			format:        m.format,
 		}
 
 		if wasRewritten {
-			state.links["alternate"] = reqURL
+			state.warning = m.warning(r)
+		}
+
+		if !fromRewriter {
+			if err := state.openPage(); err != nil {
+				m.errorHandler(w, r, err)
+				return
+			}
 		}
 
 		// serve the request with wrapped response writer and updated context
-		next.ServeHTTP(newResponseWriter(w, state), r.WithContext(context.WithValue(r.Context(), stateKey, state)))
+		state.request = r.WithContext(context.WithValue(r.Context(), stateKey, state))
+		next.ServeHTTP(newResponseWriter(w, state), state.request)
 	})
 }
 
-func (m *middleware) enforceRestrictions(reqURL url.URL) url.URL {
-	maxItems, ok := maxItems(reqURL)
+// inQuery reports whether any query parameter of u has the given value.
+func inQuery(u url.URL, value string) bool {
+	for _, values := range u.Query() {
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (m *middleware) rewrite(u url.URL) (url.URL, string, bool) {
+	for _, scheme := range m.schemes {
+		if rewritten, ok := scheme.rewriter(u); ok {
+			return rewritten, scheme.name, true
+		}
+	}
+
+	return u, "", false
+}
+
+func (m *middleware) enforceRestrictions(reqURL url.URL) (url.URL, error) {
+	value := reqURL.Query().Get(m.params.MaxItems)
+	if value == "" {
+		return m.params.setMaxItems(reqURL, m.maxItemsDefault), nil
+	}
+
+	maxItems, ok := m.params.maxItems(reqURL)
 	if !ok {
-		return setMaxItems(reqURL, m.maxItemsDefault)
+		return reqURL, &ParamError{Param: m.params.MaxItems, Value: value, Err: ErrNotInteger}
+	}
+
+	if maxItems < m.maxItemsMinimum {
+		return reqURL, &ParamError{Param: m.params.MaxItems, Value: value, Err: fmt.Errorf("%w of %d", ErrBelowMinimum, m.maxItemsMinimum)}
 	}
 
 	if maxItems > m.maxItemsLimit {
-		return setMaxItems(reqURL, m.maxItemsLimit)
+		return m.params.setMaxItems(reqURL, m.maxItemsLimit), nil
 	}
 
-	return reqURL
+	return reqURL, nil
 }
@@ -1,12 +1,16 @@
 package pagination
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMaxItems(t *testing.T) {
@@ -69,6 +73,90 @@ func TestMaxItems(t *testing.T) {
 	}
 }
 
+func TestMaxItemsValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []MiddlewareOpt
+		url          string
+		expectStatus int
+		expectErr    error
+	}{
+		{
+			name:         "valid",
+			opts:         []MiddlewareOpt{},
+			url:          "http://example.com/items?maxItems=1",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "empty",
+			opts:         []MiddlewareOpt{},
+			url:          "http://example.com/items?maxItems=",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "not a number",
+			opts:         []MiddlewareOpt{},
+			url:          "http://example.com/items?maxItems=abc",
+			expectStatus: http.StatusBadRequest,
+			expectErr:    ErrNotInteger,
+		},
+		{
+			name:         "zero",
+			opts:         []MiddlewareOpt{},
+			url:          "http://example.com/items?maxItems=0",
+			expectStatus: http.StatusBadRequest,
+			expectErr:    ErrBelowMinimum,
+		},
+		{
+			name:         "negative",
+			opts:         []MiddlewareOpt{},
+			url:          "http://example.com/items?maxItems=-5",
+			expectStatus: http.StatusBadRequest,
+			expectErr:    ErrBelowMinimum,
+		},
+		{
+			name:         "custom minimum",
+			opts:         []MiddlewareOpt{WithMaxItemsMinimum(5)},
+			url:          "http://example.com/items?maxItems=4",
+			expectStatus: http.StatusBadRequest,
+			expectErr:    ErrBelowMinimum,
+		},
+		{
+			name:         "custom minimum valid",
+			opts:         []MiddlewareOpt{WithMaxItemsMinimum(5)},
+			url:          "http://example.com/items?maxItems=5",
+			expectStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(tt.opts...)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.GreaterOrEqual(t, MaxItems(r), 1)
+			})).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+
+			if tt.expectErr != nil {
+				assert.Contains(t, rec.Body.String(), strings.TrimPrefix(tt.expectErr.Error(), "pagination: "))
+			}
+		})
+	}
+}
+
+func TestParamError(t *testing.T) {
+	err := error(&ParamError{Param: "maxItems", Value: "0", Err: fmt.Errorf("%w of %d", ErrBelowMinimum, 1)})
+
+	assert.ErrorIs(t, err, ErrBelowMinimum)
+	assert.Equal(t, `pagination: invalid maxItems parameter "0": below the minimum of 1`, err.Error())
+}
+
 func TestPage(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -557,3 +645,925 @@ func equalHeaders(t *testing.T, expect, actual http.Header) {
 func str(s string) *string {
 	return &s
 }
+
+func TestTokenCodecMiddleware(t *testing.T) {
+	var (
+		codec   = NewTokenCodec([]byte("secret"))
+		expired = NewTokenCodec([]byte("secret"))
+	)
+
+	expired.now = func() time.Time { return time.Now().Add(-2 * time.Hour) }
+
+	tests := []struct {
+		name         string
+		page         func(t *testing.T) string
+		expectStatus int
+		expectPage   string
+	}{
+		{
+			name:         "first page",
+			page:         func(t *testing.T) string { return "" },
+			expectStatus: http.StatusOK,
+			expectPage:   "",
+		},
+		{
+			name:         "sealed page",
+			page:         func(t *testing.T) string { return codec.sealToken([]byte("abc"), nil) },
+			expectStatus: http.StatusOK,
+			expectPage:   "abc",
+		},
+		{
+			name:         "unsealed page",
+			page:         func(t *testing.T) string { return "abc" },
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "expired page",
+			page:         func(t *testing.T) string { return expired.sealToken([]byte("abc"), nil) },
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(WithTokenCodec(NewTokenCodec([]byte("secret"), WithTTL(time.Hour))))
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", "http://example.com/items?page="+url.QueryEscape(tt.page(t)), nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.expectPage, Page(r))
+				SetNext(r, "def")
+
+				w.Write([]byte("test"))
+			})).ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectStatus, rec.Code)
+			if tt.expectStatus != http.StatusOK {
+				return
+			}
+
+			// following the next link should give the handler the page it set
+			links := rec.Header().Values("Link")
+			require.Len(t, links, 1)
+
+			next, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(links[0], "<"), `>; rel="next"`))
+			require.NoError(t, err)
+			assert.NotEqual(t, "def", next.Query().Get("page"))
+
+			b, err := codec.openToken(next.Query().Get("page"), nil)
+			require.NoError(t, err)
+			assert.Equal(t, "def", string(b))
+		})
+	}
+}
+
+func TestTokenCodecWithRewriter(t *testing.T) {
+	var (
+		codec     = NewTokenCodec([]byte("secret"))
+		principal = func(r *http.Request) string { return r.Header.Get("X-User") }
+	)
+
+	sealFor := func(user string) string {
+		req := httptest.NewRequest("GET", "http://example.com/items", nil)
+		req.Header.Set("X-User", user)
+
+		m := &middleware{params: DefaultParams, principal: principal}
+		return url.QueryEscape(codec.sealToken([]byte("abc"), m.binding(req, *req.URL)))
+	}
+
+	// perPage moves per_page to maxItems, passing any page token sent by the client through unchanged
+	perPage := func(u url.URL) (url.URL, bool) {
+		q := u.Query()
+		if !q.Has("per_page") {
+			return u, false
+		}
+
+		q.Set("maxItems", q.Get("per_page"))
+		q.Del("per_page")
+
+		u.RawQuery = q.Encode()
+		return u, true
+	}
+
+	// cursor copies the legacy cursor parameter into the page parameter, as the shim in the examples does
+	cursor := func(u url.URL) (url.URL, bool) {
+		q := u.Query()
+		if !q.Has("cursor") {
+			return u, false
+		}
+
+		q.Set("page", q.Get("cursor"))
+		q.Del("cursor")
+
+		u.RawQuery = q.Encode()
+		return u, true
+	}
+
+	tests := []struct {
+		name         string
+		rewriter     Rewriter
+		url          string
+		expectStatus int
+		expectPage   string
+	}{
+		{
+			name:         "forged page passed through",
+			rewriter:     perPage,
+			url:          "http://example.com/items?page=forged&per_page=10",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "sealed page passed through",
+			rewriter:     perPage,
+			url:          "http://example.com/items?per_page=10&page=" + sealFor("alice"),
+			expectStatus: http.StatusOK,
+			expectPage:   "abc",
+		},
+		{
+			name:         "sealed page for another principal passed through",
+			rewriter:     perPage,
+			url:          "http://example.com/items?per_page=10&page=" + sealFor("bob"),
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "forged page copied by rewriter",
+			rewriter:     cursor,
+			url:          "http://example.com/items?cursor=forged",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "forged offset token copied by rewriter",
+			rewriter:     cursor,
+			url:          "http://example.com/items?cursor=" + OffsetToken(20),
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "sealed page copied by rewriter",
+			rewriter:     cursor,
+			url:          "http://example.com/items?cursor=" + sealFor("alice"),
+			expectStatus: http.StatusOK,
+			expectPage:   "abc",
+		},
+		{
+			name:         "page created by rewriter",
+			rewriter:     OffsetLimitRewriter(DefaultParams),
+			url:          "http://example.com/items?offset=20&limit=10",
+			expectStatus: http.StatusOK,
+			expectPage:   OffsetToken(20),
+		},
+		{
+			name:         "forged page replaced by rewriter",
+			rewriter:     OffsetLimitRewriter(DefaultParams),
+			url:          "http://example.com/items?page=forged&offset=20&limit=10",
+			expectStatus: http.StatusOK,
+			expectPage:   OffsetToken(20),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(WithTokenCodec(codec), WithPrincipalBinding(principal), WithBackwardsCompatibility(tt.rewriter))
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			req.Header.Set("X-User", "alice")
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.expectPage, Page(r))
+				w.Write([]byte("test"))
+			})).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectStatus, rec.Code)
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []MiddlewareOpt
+		cursor testCursor
+	}{
+		{
+			name:   "default format",
+			opts:   []MiddlewareOpt{},
+			cursor: testCursor{ID: "abc", Offset: 10},
+		},
+		{
+			name:   "binary format",
+			opts:   []MiddlewareOpt{WithTokenFormat(Binary)},
+			cursor: testCursor{ID: "abc", Offset: 10},
+		},
+		{
+			name:   "compressed binary format with codec",
+			opts:   []MiddlewareOpt{WithTokenFormat(Compressed(Binary)), WithTokenCodec(NewTokenCodec([]byte("secret")))},
+			cursor: testCursor{ID: "abc", Offset: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMiddleware(tt.opts...)
+
+			handler := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				cursor, err := Decode[testCursor](r)
+				require.NoError(t, err)
+
+				if cursor == (testCursor{}) {
+					token, err := Encode(r, tt.cursor)
+					require.NoError(t, err)
+
+					SetNext(r, token)
+				} else {
+					assert.Equal(t, tt.cursor, cursor)
+				}
+
+				w.Write([]byte("test"))
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com/items", nil))
+
+			links := rec.Header().Values("Link")
+			require.Len(t, links, 1)
+			next := strings.TrimSuffix(strings.TrimPrefix(links[0], "<"), `>; rel="next"`)
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", next, nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Values("Link"))
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	token, err := EncodeToken(struct {
+		ID string `json:"id"`
+	}{ID: "abc"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		url          string
+		expectCursor testCursor
+		expectErr    bool
+	}{
+		{
+			name:         "first page",
+			url:          "http://example.com/items",
+			expectCursor: testCursor{},
+		},
+		{
+			name:         "with page",
+			url:          "http://example.com/items?page=" + token,
+			expectCursor: testCursor{ID: "abc"},
+		},
+		{
+			name:      "invalid page",
+			url:       "http://example.com/items?page=abc",
+			expectErr: true,
+		},
+		{
+			name:      "malformed base64",
+			url:       "http://example.com/items?page=a",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware()
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				cursor, err := Decode[testCursor](r)
+				if tt.expectErr {
+					assert.ErrorIs(t, err, ErrInvalidToken)
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectCursor, cursor)
+			})).ServeHTTP(rec, req)
+		})
+	}
+}
+
+func TestBinding(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []MiddlewareOpt
+		issuedFor    string
+		replayedAt   string
+		expectStatus int
+	}{
+		{
+			name:         "unbound different query",
+			opts:         []MiddlewareOpt{},
+			issuedFor:    "http://example.com/items?status=open",
+			replayedAt:   "http://example.com/items?status=closed",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "query bound same query",
+			opts:         []MiddlewareOpt{WithQueryBinding()},
+			issuedFor:    "http://example.com/items?status=open",
+			replayedAt:   "http://example.com/items?status=open",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "query bound different max items",
+			opts:         []MiddlewareOpt{WithQueryBinding()},
+			issuedFor:    "http://example.com/items?status=open&maxItems=10",
+			replayedAt:   "http://example.com/items?status=open&maxItems=20",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "query bound different query",
+			opts:         []MiddlewareOpt{WithQueryBinding()},
+			issuedFor:    "http://example.com/items?status=open",
+			replayedAt:   "http://example.com/items?status=closed",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "query bound different path",
+			opts:         []MiddlewareOpt{WithQueryBinding()},
+			issuedFor:    "http://example.com/items?status=open",
+			replayedAt:   "http://example.com/other?status=open",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "path bound different path",
+			opts:         []MiddlewareOpt{WithPathBinding()},
+			issuedFor:    "http://example.com/items?status=open",
+			replayedAt:   "http://example.com/other?status=open",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "path bound different query",
+			opts:         []MiddlewareOpt{WithPathBinding()},
+			issuedFor:    "http://example.com/items?status=open",
+			replayedAt:   "http://example.com/items?status=closed",
+			expectStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(append(tt.opts, WithTokenCodec(NewTokenCodec([]byte("secret"))))...)
+				rec = httptest.NewRecorder()
+			)
+
+			handler := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				SetNext(r, "abc")
+				w.Write([]byte("test"))
+			}))
+
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.issuedFor, nil))
+
+			next, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(rec.Header().Get("Link"), "<"), `>; rel="next"`))
+			require.NoError(t, err)
+
+			replay, err := url.Parse(tt.replayedAt)
+			require.NoError(t, err)
+			withPage := DefaultParams.setPage(*replay, next.Query().Get("page"))
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", withPage.String(), nil))
+			assert.Equal(t, tt.expectStatus, rec.Code)
+		})
+	}
+}
+
+func TestPrincipalBinding(t *testing.T) {
+	tests := []struct {
+		name         string
+		issuedTo     string
+		usedBy       string
+		expectStatus int
+	}{
+		{
+			name:         "same principal",
+			issuedTo:     "tenant-a",
+			usedBy:       "tenant-a",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "different principal",
+			issuedTo:     "tenant-a",
+			usedBy:       "tenant-b",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "anonymous principal",
+			issuedTo:     "tenant-a",
+			usedBy:       "",
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m = NewMiddleware(
+					WithTokenCodec(NewTokenCodec([]byte("secret"), WithEncryption())),
+					WithPrincipalBinding(func(r *http.Request) string { return r.Header.Get("X-Tenant") }),
+				)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", "http://example.com/items", nil)
+			)
+
+			handler := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				SetNext(r, "abc")
+				w.Write([]byte("test"))
+			}))
+
+			req.Header.Set("X-Tenant", tt.issuedTo)
+			handler.ServeHTTP(rec, req)
+
+			next := strings.TrimSuffix(strings.TrimPrefix(rec.Header().Get("Link"), "<"), `>; rel="next"`)
+
+			rec = httptest.NewRecorder()
+			req = httptest.NewRequest("GET", next, nil)
+			req.Header.Set("X-Tenant", tt.usedBy)
+
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectStatus, rec.Code)
+		})
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []MiddlewareOpt
+		url         string
+		expectCode  int
+		expectType  string
+		expectBody  string
+		expectParam string
+	}{
+		{
+			name:       "problem invalid max items",
+			opts:       []MiddlewareOpt{},
+			url:        "http://example.com/items?maxItems=abc",
+			expectCode: http.StatusBadRequest,
+			expectType: "application/problem+json",
+			expectBody: `{
+				"type": "about:blank",
+				"title": "Bad Request",
+				"status": 400,
+				"detail": "The maxItems parameter is invalid.",
+				"invalid-params": [{"name": "maxItems", "reason": "must be a whole number"}]
+			}`,
+		},
+		{
+			name:       "problem renamed param",
+			opts:       []MiddlewareOpt{WithParams(Params{MaxItems: "limit"})},
+			url:        "http://example.com/items?limit=-1",
+			expectCode: http.StatusBadRequest,
+			expectType: "application/problem+json",
+			expectBody: `{
+				"type": "about:blank",
+				"title": "Bad Request",
+				"status": 400,
+				"detail": "The limit parameter is invalid.",
+				"invalid-params": [{"name": "limit", "reason": "below the minimum of 1"}]
+			}`,
+		},
+		{
+			name:       "problem invalid page",
+			opts:       []MiddlewareOpt{WithTokenCodec(NewTokenCodec([]byte("secret")))},
+			url:        "http://example.com/items?page=abc",
+			expectCode: http.StatusBadRequest,
+			expectType: "application/problem+json",
+			expectBody: `{
+				"type": "about:blank",
+				"title": "Bad Request",
+				"status": 400,
+				"detail": "The page parameter is invalid.",
+				"invalid-params": [{"name": "page", "reason": "invalid page token"}]
+			}`,
+		},
+		{
+			name: "custom handler",
+			opts: []MiddlewareOpt{
+				WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+					var paramErr *ParamError
+					if assert.ErrorAs(t, err, &paramErr) {
+						w.Header().Set("X-Invalid-Param", paramErr.Param)
+					}
+
+					w.WriteHeader(http.StatusUnprocessableEntity)
+				}),
+			},
+			url:         "http://example.com/items?maxItems=0",
+			expectCode:  http.StatusUnprocessableEntity,
+			expectParam: "maxItems",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(tt.opts...)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler should not be called")
+			})).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectCode, rec.Code)
+			assert.Equal(t, tt.expectParam, rec.Header().Get("X-Invalid-Param"))
+
+			if tt.expectBody != "" {
+				assert.Equal(t, tt.expectType, rec.Header().Get("Content-Type"))
+				assert.JSONEq(t, tt.expectBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestParams(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []MiddlewareOpt
+		url            string
+		expectMaxItems int
+		expectPage     string
+		expectHeaders  map[string][]string
+	}{
+		{
+			name:           "renamed defaults",
+			opts:           []MiddlewareOpt{WithParams(Params{MaxItems: "limit", Page: "cursor"})},
+			url:            "http://example.com/items",
+			expectMaxItems: 100,
+			expectPage:     "",
+			expectHeaders: map[string][]string{
+				"Content-Type": {"text/plain; charset=utf-8"},
+				"Link":         {`<http://example.com/items?cursor=next&limit=100>; rel="next"`},
+			},
+		},
+		{
+			name:           "renamed with values",
+			opts:           []MiddlewareOpt{WithParams(Params{MaxItems: "page_size", Page: "page_token"})},
+			url:            "http://example.com/items?page_size=10&page_token=abc&maxItems=50&page=def",
+			expectMaxItems: 10,
+			expectPage:     "abc",
+			expectHeaders: map[string][]string{
+				"Content-Type": {"text/plain; charset=utf-8"},
+				"Link":         {`<http://example.com/items?maxItems=50&page=def&page_size=10&page_token=next>; rel="next"`},
+			},
+		},
+		{
+			name:           "renamed page only",
+			opts:           []MiddlewareOpt{WithParams(Params{Page: "cursor"})},
+			url:            "http://example.com/items?maxItems=10&cursor=abc",
+			expectMaxItems: 10,
+			expectPage:     "abc",
+			expectHeaders: map[string][]string{
+				"Content-Type": {"text/plain; charset=utf-8"},
+				"Link":         {`<http://example.com/items?cursor=next&maxItems=10>; rel="next"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(tt.opts...)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.expectMaxItems, MaxItems(r))
+				assert.Equal(t, tt.expectPage, Page(r))
+
+				SetNext(r, "next")
+				w.Write([]byte("test"))
+			})).ServeHTTP(rec, req)
+
+			equalHeaders(t, http.Header(tt.expectHeaders), rec.Header())
+		})
+	}
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	var (
+		deprecated = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		sunset     = time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)
+		legacy     = WithBackwardsCompatibility(OffsetLimitRewriter(DefaultParams))
+	)
+
+	tests := []struct {
+		name          string
+		opts          []MiddlewareOpt
+		url           string
+		expectHeaders map[string][]string
+	}{
+		{
+			name: "not rewritten",
+			opts: []MiddlewareOpt{
+				legacy,
+				WithDeprecation(deprecated, "https://example.com/docs/pagination"),
+				WithSunset(sunset, "https://example.com/docs/sunset"),
+			},
+			url: "http://example.com/items",
+			expectHeaders: map[string][]string{
+				"Content-Type": {"text/plain; charset=utf-8"},
+			},
+		},
+		{
+			name: "rewritten with docs",
+			opts: []MiddlewareOpt{
+				legacy,
+				WithDeprecation(deprecated, "https://example.com/docs/pagination"),
+				WithSunset(sunset, "https://example.com/docs/sunset"),
+			},
+			url: "http://example.com/items?limit=10",
+			expectHeaders: map[string][]string{
+				"Content-Type": {"text/plain; charset=utf-8"},
+				"Deprecation":  {"@1704067200"},
+				"Sunset":       {"Tue, 31 Dec 2024 23:59:59 GMT"},
+				"Link": {
+					`<http://example.com/items?maxItems=10>; rel="alternate"`,
+					`<https://example.com/docs/pagination>; rel="deprecation"`,
+					`<https://example.com/docs/sunset>; rel="sunset"`,
+				},
+				"Warning": {`299 - "Deprecated pagination method. Please use alternate method."`},
+			},
+		},
+		{
+			name: "rewritten without docs",
+			opts: []MiddlewareOpt{
+				legacy,
+				WithDeprecation(deprecated, ""),
+				WithSunset(sunset.In(time.FixedZone("EST", -5*60*60)), ""),
+			},
+			url: "http://example.com/items?limit=10",
+			expectHeaders: map[string][]string{
+				"Content-Type": {"text/plain; charset=utf-8"},
+				"Deprecation":  {"@1704067200"},
+				"Sunset":       {"Tue, 31 Dec 2024 23:59:59 GMT"},
+				"Link":         {`<http://example.com/items?maxItems=10>; rel="alternate"`},
+				"Warning":      {`299 - "Deprecated pagination method. Please use alternate method."`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(tt.opts...)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("test"))
+			})).ServeHTTP(rec, req)
+
+			equalHeaders(t, http.Header(tt.expectHeaders), rec.Header())
+		})
+	}
+}
+
+func TestWarningCatalog(t *testing.T) {
+	catalog := map[string]string{
+		"en":    "Deprecated pagination method. Please use alternate method.",
+		"fr":    "Méthode de pagination obsolète. Veuillez utiliser la méthode alternative.",
+		"pt-BR": "Método de paginação obsoleto. Por favor, use o método alternativo.",
+		"de":    `Veraltete "Paginierung".`,
+	}
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		expectWarning  string
+	}{
+		{
+			name:           "no preference",
+			acceptLanguage: "",
+			expectWarning:  `299 - "Deprecated pagination method. Please use alternate method."`,
+		},
+		{
+			name:           "exact match",
+			acceptLanguage: "fr",
+			expectWarning:  `299 - "Méthode de pagination obsolète. Veuillez utiliser la méthode alternative."`,
+		},
+		{
+			name:           "regional match",
+			acceptLanguage: "pt-br",
+			expectWarning:  `299 - "Método de paginação obsoleto. Por favor, use o método alternativo."`,
+		},
+		{
+			name:           "base language match",
+			acceptLanguage: "fr-CA",
+			expectWarning:  `299 - "Méthode de pagination obsolète. Veuillez utiliser la méthode alternative."`,
+		},
+		{
+			name:           "quality order",
+			acceptLanguage: "es;q=0.9, fr;q=0.5, pt-BR;q=0.8",
+			expectWarning:  `299 - "Método de paginação obsoleto. Por favor, use o método alternativo."`,
+		},
+		{
+			name:           "not acceptable",
+			acceptLanguage: "fr;q=0, *;q=0.5",
+			expectWarning:  `299 - "Deprecated pagination method. Please use alternate method."`,
+		},
+		{
+			name:           "quoted message",
+			acceptLanguage: "de",
+			expectWarning:  `299 - "Veraltete \"Paginierung\"."`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m = NewMiddleware(
+					WithBackwardsCompatibility(OffsetLimitRewriter(DefaultParams)),
+					WithWarningCatalog(catalog, "en"),
+				)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", "http://example.com/items?limit=10", nil)
+			)
+
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("test"))
+			})).ServeHTTP(rec, req)
+
+			assert.Equal(t, []string{tt.expectWarning}, rec.Header().Values("Warning"))
+		})
+	}
+}
+
+func TestLegacyHook(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		expectUsage []LegacyUsage
+	}{
+		{
+			name:        "not rewritten",
+			url:         "http://example.com/items?maxItems=10",
+			expectUsage: nil,
+		},
+		{
+			name: "offset scheme",
+			url:  "http://example.com/items?offset=20&limit=10",
+			expectUsage: []LegacyUsage{{
+				Scheme:    "offset",
+				Original:  url.URL{Scheme: "http", Host: "example.com", Path: "/items", RawQuery: "offset=20&limit=10"},
+				Rewritten: url.URL{Scheme: "http", Host: "example.com", Path: "/items", RawQuery: "maxItems=10&page=" + OffsetToken(20)},
+			}},
+		},
+		{
+			name: "rejected request",
+			url:  "http://example.com/items?limit=0",
+			expectUsage: []LegacyUsage{{
+				Scheme:    "offset",
+				Original:  url.URL{Scheme: "http", Host: "example.com", Path: "/items", RawQuery: "limit=0"},
+				Rewritten: url.URL{Scheme: "http", Host: "example.com", Path: "/items", RawQuery: "maxItems=0"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				usage []LegacyUsage
+				m     = NewMiddleware(
+					WithLegacyScheme("offset", OffsetLimitRewriter(DefaultParams)),
+					WithLegacyHook(func(u LegacyUsage) {
+						assert.NotNil(t, u.Request)
+						u.Request = nil
+
+						usage = append(usage, u)
+					}),
+				)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("test"))
+			})).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectUsage, usage)
+		})
+	}
+}
+
+func TestLegacyCutoff(t *testing.T) {
+	legacy := WithLegacyScheme("offset", OffsetLimitRewriter(DefaultParams))
+
+	tests := []struct {
+		name          string
+		opts          []MiddlewareOpt
+		url           string
+		expectCode    int
+		expectHeaders map[string][]string
+		expectBody    string
+	}{
+		{
+			name:       "before cutoff",
+			opts:       []MiddlewareOpt{legacy, WithLegacyCutoff(time.Now().Add(time.Hour), http.StatusGone)},
+			url:        "http://example.com/items?limit=10",
+			expectCode: http.StatusOK,
+			expectHeaders: map[string][]string{
+				"Content-Type": {"text/plain; charset=utf-8"},
+				"Link":         {`<http://example.com/items?maxItems=10>; rel="alternate"`},
+				"Warning":      {`299 - "Deprecated pagination method. Please use alternate method."`},
+			},
+		},
+		{
+			name:       "after cutoff",
+			opts:       []MiddlewareOpt{legacy, WithLegacyCutoff(time.Now().Add(-time.Hour), http.StatusGone)},
+			url:        "http://example.com/items?limit=10",
+			expectCode: http.StatusGone,
+			expectHeaders: map[string][]string{
+				"Content-Type": {"application/problem+json"},
+				"Link":         {`<http://example.com/items?maxItems=10>; rel="alternate"`},
+			},
+			expectBody: `{
+				"type": "about:blank",
+				"title": "Gone",
+				"status": 410,
+				"detail": "This pagination method is no longer supported. Please use alternate method.",
+				"alternate": "http://example.com/items?maxItems=10"
+			}`,
+		},
+		{
+			name:       "after cutoff not legacy",
+			opts:       []MiddlewareOpt{legacy, WithLegacyCutoff(time.Now().Add(-time.Hour), http.StatusGone)},
+			url:        "http://example.com/items?maxItems=10",
+			expectCode: http.StatusOK,
+			expectHeaders: map[string][]string{
+				"Content-Type": {"text/plain; charset=utf-8"},
+			},
+		},
+		{
+			name:       "after cutoff invalid status",
+			opts:       []MiddlewareOpt{legacy, WithLegacyCutoff(time.Now().Add(-time.Hour), 0)},
+			url:        "http://example.com/items?limit=10",
+			expectCode: http.StatusGone,
+		},
+		{
+			name:       "after cutoff invalid limit",
+			opts:       []MiddlewareOpt{legacy, WithLegacyCutoff(time.Now().Add(-time.Hour), http.StatusGone)},
+			url:        "http://example.com/items?limit=abc",
+			expectCode: http.StatusGone,
+			expectHeaders: map[string][]string{
+				"Content-Type": {"application/problem+json"},
+				"Link":         {`<http://example.com/items?maxItems=abc>; rel="alternate"`},
+			},
+		},
+		{
+			name:       "after cutoff with codec",
+			opts:       []MiddlewareOpt{legacy, WithLegacyCutoff(time.Now().Add(-time.Hour), http.StatusBadRequest), WithTokenCodec(NewTokenCodec([]byte("secret")))},
+			url:        "http://example.com/items?offset=20&limit=10",
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(tt.opts...)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("test"))
+			})).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectCode, rec.Code)
+
+			if tt.expectHeaders != nil {
+				equalHeaders(t, http.Header(tt.expectHeaders), rec.Header())
+			}
+
+			if tt.expectBody != "" {
+				assert.JSONEq(t, tt.expectBody, rec.Body.String())
+			}
+
+			// the alternate link must be usable, so its page token is sealed like any other
+			assert.NotContains(t, rec.Header().Get("Link"), OffsetToken(20))
+		})
+	}
+}
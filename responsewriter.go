@@ -26,12 +26,28 @@ func (r *responseWriter) writePageHeaders() {
 		header := r.ResponseWriter.Header()
 
 		if r.state.wasRewritten {
+			if r.state.responder != nil {
+				r.state.responder(LegacyResponse{
+					Request: r.state.request,
+					Scheme:  r.state.scheme,
+					Header:  header,
+					Links:   r.state.links,
+				})
+			}
+
 			r.state.links["alternate"] = r.state.current
-			header.Add("Warning", `299 - "Deprecated pagination method. Please use alternate method."`)
+			header.Add("Warning", warningHeader(r.state.warning))
+
+			for k, values := range r.state.legacyHeaders {
+				for _, v := range values {
+					header.Add(k, v)
+				}
+			}
 		}
 
 		for k, v := range r.state.links {
-			header.Add("Link", `<`+v.String()+`>; rel="`+k+`"`)
+			sealed := r.state.sealPage(v)
+			header.Add("Link", Link{URL: sealed.String(), Rel: []string{k}}.String())
 		}
 	})
 }
@@ -0,0 +1,190 @@
+package pagination
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// OffsetLimitRewriter returns a Rewriter for legacy requests paginated with offset and limit query parameters,
+// such as ?offset=20&limit=10.
+//
+// The offset is converted into a page token, which handlers can read back with Offset, and the limit is
+// moved to the maxItems parameter. The params should be the same as those given to the middleware.
+// Requests with an offset that is not a positive whole number are left unchanged.
+func OffsetLimitRewriter(p Params) Rewriter {
+	return func(u url.URL) (url.URL, bool) {
+		q := u.Query()
+		if !q.Has("offset") && !q.Has("limit") {
+			return u, false
+		}
+
+		offset := 0
+		if value := q.Get("offset"); value != "" {
+			var err error
+			if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+				return u, false
+			}
+		}
+
+		if limit := q.Get("limit"); limit != "" {
+			q.Set(p.MaxItems, limit)
+		}
+
+		q.Del("offset")
+		q.Del("limit")
+		q.Del(p.Page)
+
+		if offset > 0 {
+			q.Set(p.Page, OffsetToken(offset))
+		}
+
+		u.RawQuery = q.Encode()
+		return u, true
+	}
+}
+
+// OffsetLimitResponder returns a LegacyResponder for requests rewritten by OffsetLimitRewriter,
+// which writes the links set by the handler as offset and limit URLs, as legacy clients expect.
+//
+// Links are expected to hold page tokens produced by OffsetToken. Any that do not are left unchanged.
+func OffsetLimitResponder(p Params) LegacyResponder {
+	return func(resp LegacyResponse) {
+		for rel, link := range resp.Links {
+			q := link.Query()
+
+			var offset int
+			if token := q.Get(p.Page); token != "" {
+				var ok bool
+				if offset, ok = parseOffsetToken(token); !ok {
+					continue
+				}
+			}
+
+			if limit := q.Get(p.MaxItems); limit != "" {
+				q.Set("limit", limit)
+			}
+
+			if offset > 0 {
+				q.Set("offset", strconv.Itoa(offset))
+			}
+
+			q.Del(p.MaxItems)
+			q.Del(p.Page)
+
+			link.RawQuery = q.Encode()
+			resp.Header.Add("Link", Link{URL: link.String(), Rel: []string{rel}}.String())
+			delete(resp.Links, rel)
+		}
+	}
+}
+
+// PageNumberRewriter returns a Rewriter for legacy requests paginated with a page number and page size,
+// such as ?page=3&per_page=50.
+//
+// The page number is converted into a page token holding the offset of the first item on that page, which
+// handlers can read back with Offset, and the page size is moved to the maxItems parameter. When per_page
+// is omitted, the page size is taken from maxItems if present, or perPage otherwise.
+// The params should be the same as those given to the middleware, and maxPerPage the same as the limit
+// given to WithMaxItemsLimit. Larger page sizes are reduced to maxPerPage, as the middleware would,
+// so that the offset agrees with the number of items served on each page.
+//
+// As the legacy page parameter may collide with the specification's own, only page numbers (a positive
+// whole number) are treated as legacy, along with per_page alone for the first page. Page tokens must
+// therefore never be purely numeric.
+func PageNumberRewriter(p Params, perPage, maxPerPage int) Rewriter {
+	return func(u url.URL) (url.URL, bool) {
+		q := u.Query()
+
+		// a page that isn't a number is the specification's own, so its token must be verified as usual
+		number, numbered := positiveInt(q.Get("page"))
+		if !numbered && (q.Has("page") || !q.Has("per_page")) {
+			return u, false
+		}
+
+		size := perPage
+		if value := q.Get("per_page"); value != "" {
+			var ok bool
+			if size, ok = positiveInt(value); !ok {
+				return u, false
+			}
+		} else if value, ok := positiveInt(q.Get(p.MaxItems)); ok {
+			size = value
+		}
+
+		if size > maxPerPage {
+			size = maxPerPage
+		}
+
+		// the page size is always set, so the offset and number of items returned agree
+		q.Set(p.MaxItems, strconv.Itoa(size))
+
+		// page numbers so large their offset would overflow can't be served
+		if numbered && number-1 > math.MaxInt/size {
+			return u, false
+		}
+
+		q.Del("page")
+		q.Del("per_page")
+		q.Del(p.Page)
+
+		if numbered && number > 1 {
+			q.Set(p.Page, OffsetToken((number-1)*size))
+		}
+
+		u.RawQuery = q.Encode()
+		return u, true
+	}
+}
+
+func positiveInt(value string) (int, bool) {
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 1 {
+		return 0, false
+	}
+
+	return i, true
+}
+
+// OffsetToken returns a page token for the given offset, as produced by the legacy rewriters.
+//
+// Handlers serving rewritten requests should use it to set their links, so subsequent pages can
+// also be read with Offset.
+func OffsetToken(offset int) string {
+	// encoding an int can't fail
+	token, _ := EncodeToken(offsetCursor{Offset: offset})
+	return token
+}
+
+// Offset returns the offset held by the page token of the request, as produced by OffsetToken.
+//
+// When there is no page token, such as on a request for the first page, the offset is 0.
+func Offset(r *http.Request) (int, error) {
+	token := Page(r)
+	if token == "" {
+		return 0, nil
+	}
+
+	var cursor offsetCursor
+	if err := DecodeToken(token, &cursor); err != nil {
+		return 0, err
+	}
+
+	return cursor.Offset, nil
+}
+
+// parseOffsetToken returns the offset held by a page token produced by OffsetToken, or false for any other
+// token, including those that happen to decode into an offsetCursor.
+func parseOffsetToken(token string) (int, bool) {
+	var cursor offsetCursor
+	if err := DecodeToken(token, &cursor); err != nil || cursor.Offset < 0 || OffsetToken(cursor.Offset) != token {
+		return 0, false
+	}
+
+	return cursor.Offset, true
+}
+
+type offsetCursor struct {
+	Offset int `json:"offset"`
+}
@@ -0,0 +1,483 @@
+package pagination
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetLimitRewriter(t *testing.T) {
+	tests := []struct {
+		name            string
+		params          Params
+		url             string
+		expectURL       string
+		expectRewritten bool
+	}{
+		{
+			name:            "not legacy",
+			params:          DefaultParams,
+			url:             "http://example.com/items?maxItems=10&page=abc",
+			expectURL:       "http://example.com/items?maxItems=10&page=abc",
+			expectRewritten: false,
+		},
+		{
+			name:            "offset and limit",
+			params:          DefaultParams,
+			url:             "http://example.com/items?offset=20&limit=10&status=open",
+			expectURL:       "http://example.com/items?maxItems=10&page=" + OffsetToken(20) + "&status=open",
+			expectRewritten: true,
+		},
+		{
+			name:            "limit only",
+			params:          DefaultParams,
+			url:             "http://example.com/items?limit=10",
+			expectURL:       "http://example.com/items?maxItems=10",
+			expectRewritten: true,
+		},
+		{
+			name:            "offset only",
+			params:          DefaultParams,
+			url:             "http://example.com/items?offset=20",
+			expectURL:       "http://example.com/items?page=" + OffsetToken(20),
+			expectRewritten: true,
+		},
+		{
+			name:            "zero offset",
+			params:          DefaultParams,
+			url:             "http://example.com/items?offset=0&limit=10",
+			expectURL:       "http://example.com/items?maxItems=10",
+			expectRewritten: true,
+		},
+		{
+			name:            "invalid offset",
+			params:          DefaultParams,
+			url:             "http://example.com/items?offset=abc&limit=10",
+			expectURL:       "http://example.com/items?offset=abc&limit=10",
+			expectRewritten: false,
+		},
+		{
+			name:            "negative offset",
+			params:          DefaultParams,
+			url:             "http://example.com/items?offset=-1",
+			expectURL:       "http://example.com/items?offset=-1",
+			expectRewritten: false,
+		},
+		{
+			name:            "renamed params",
+			params:          Params{MaxItems: "page_size", Page: "page_token"},
+			url:             "http://example.com/items?offset=20&limit=10",
+			expectURL:       "http://example.com/items?page_size=10&page_token=" + OffsetToken(20),
+			expectRewritten: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+
+			rewritten, ok := OffsetLimitRewriter(tt.params)(*u)
+			assert.Equal(t, tt.expectRewritten, ok)
+			assert.Equal(t, tt.expectURL, rewritten.String())
+		})
+	}
+}
+
+func TestPageNumberRewriter(t *testing.T) {
+	tests := []struct {
+		name            string
+		params          Params
+		url             string
+		expectURL       string
+		expectRewritten bool
+	}{
+		{
+			name:            "not legacy",
+			params:          DefaultParams,
+			url:             "http://example.com/items?maxItems=10&page=abc",
+			expectURL:       "http://example.com/items?maxItems=10&page=abc",
+			expectRewritten: false,
+		},
+		{
+			name:            "page and per page",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=3&per_page=50&status=open",
+			expectURL:       "http://example.com/items?maxItems=50&page=" + OffsetToken(100) + "&status=open",
+			expectRewritten: true,
+		},
+		{
+			name:            "first page",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=1&per_page=50",
+			expectURL:       "http://example.com/items?maxItems=50",
+			expectRewritten: true,
+		},
+		{
+			name:            "page only",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=3",
+			expectURL:       "http://example.com/items?maxItems=20&page=" + OffsetToken(40),
+			expectRewritten: true,
+		},
+		{
+			name:            "page with max items",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=3&maxItems=10",
+			expectURL:       "http://example.com/items?maxItems=10&page=" + OffsetToken(20),
+			expectRewritten: true,
+		},
+		{
+			name:            "per page above limit",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=2&per_page=500",
+			expectURL:       "http://example.com/items?maxItems=100&page=" + OffsetToken(100),
+			expectRewritten: true,
+		},
+		{
+			name:            "max items above limit",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=3&maxItems=500",
+			expectURL:       "http://example.com/items?maxItems=100&page=" + OffsetToken(200),
+			expectRewritten: true,
+		},
+		{
+			name:            "overflowing page",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=9223372036854775807&per_page=100",
+			expectURL:       "http://example.com/items?page=9223372036854775807&per_page=100",
+			expectRewritten: false,
+		},
+		{
+			name:            "largest page",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=" + strconv.Itoa(math.MaxInt/100+1) + "&per_page=100",
+			expectURL:       "http://example.com/items?maxItems=100&page=" + OffsetToken(math.MaxInt/100*100),
+			expectRewritten: true,
+		},
+		{
+			name:            "per page only",
+			params:          DefaultParams,
+			url:             "http://example.com/items?per_page=50",
+			expectURL:       "http://example.com/items?maxItems=50",
+			expectRewritten: true,
+		},
+		{
+			name:            "per page with token",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=abc&per_page=50",
+			expectURL:       "http://example.com/items?page=abc&per_page=50",
+			expectRewritten: false,
+		},
+		{
+			name:            "zero page",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=0",
+			expectURL:       "http://example.com/items?page=0",
+			expectRewritten: false,
+		},
+		{
+			name:            "invalid per page",
+			params:          DefaultParams,
+			url:             "http://example.com/items?page=2&per_page=abc",
+			expectURL:       "http://example.com/items?page=2&per_page=abc",
+			expectRewritten: false,
+		},
+		{
+			name:            "renamed params",
+			params:          Params{MaxItems: "page_size", Page: "page_token"},
+			url:             "http://example.com/items?page=2&per_page=10",
+			expectURL:       "http://example.com/items?page_size=10&page_token=" + OffsetToken(10),
+			expectRewritten: true,
+		},
+		{
+			name:            "renamed page param dropped",
+			params:          Params{MaxItems: "maxItems", Page: "cursor"},
+			url:             "http://example.com/items?page=1&cursor=abc&per_page=10",
+			expectURL:       "http://example.com/items?maxItems=10",
+			expectRewritten: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+
+			rewritten, ok := PageNumberRewriter(tt.params, 20, 100)(*u)
+			assert.Equal(t, tt.expectRewritten, ok)
+			assert.Equal(t, tt.expectURL, rewritten.String())
+		})
+	}
+}
+
+func TestOffset(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		expectOffset int
+		expectErr    bool
+	}{
+		{
+			name:         "first page",
+			url:          "http://example.com/items",
+			expectOffset: 0,
+		},
+		{
+			name:         "legacy offset",
+			url:          "http://example.com/items?offset=20&limit=10",
+			expectOffset: 20,
+		},
+		{
+			name:         "offset token",
+			url:          "http://example.com/items?page=" + OffsetToken(30),
+			expectOffset: 30,
+		},
+		{
+			name:      "invalid token",
+			url:       "http://example.com/items?page=abc",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(WithBackwardsCompatibility(OffsetLimitRewriter(DefaultParams)))
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				offset, err := Offset(r)
+				if tt.expectErr {
+					assert.Error(t, err)
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectOffset, offset)
+			})).ServeHTTP(rec, req)
+		})
+	}
+}
+
+func TestChainRewriters(t *testing.T) {
+	rewriter := ChainRewriters(OffsetLimitRewriter(DefaultParams), PageNumberRewriter(DefaultParams, 20, 100))
+
+	tests := []struct {
+		name            string
+		url             string
+		expectURL       string
+		expectRewritten bool
+	}{
+		{
+			name:            "first matches",
+			url:             "http://example.com/items?offset=20&limit=10",
+			expectURL:       "http://example.com/items?maxItems=10&page=" + OffsetToken(20),
+			expectRewritten: true,
+		},
+		{
+			name:            "second matches",
+			url:             "http://example.com/items?page=2&per_page=10",
+			expectURL:       "http://example.com/items?maxItems=10&page=" + OffsetToken(10),
+			expectRewritten: true,
+		},
+		{
+			name:            "none match",
+			url:             "http://example.com/items?maxItems=10&page=abc",
+			expectURL:       "http://example.com/items?maxItems=10&page=abc",
+			expectRewritten: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+
+			rewritten, ok := rewriter(*u)
+			assert.Equal(t, tt.expectRewritten, ok)
+			assert.Equal(t, tt.expectURL, rewritten.String())
+		})
+	}
+}
+
+func TestLegacyScheme(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []MiddlewareOpt
+		url          string
+		expectScheme string
+		expectOffset int
+	}{
+		{
+			name: "first scheme",
+			opts: []MiddlewareOpt{
+				WithLegacyScheme("offset", OffsetLimitRewriter(DefaultParams)),
+				WithLegacyScheme("page-number", PageNumberRewriter(DefaultParams, 20, 100)),
+			},
+			url:          "http://example.com/items?offset=20&limit=10",
+			expectScheme: "offset",
+			expectOffset: 20,
+		},
+		{
+			name: "second scheme",
+			opts: []MiddlewareOpt{
+				WithLegacyScheme("offset", OffsetLimitRewriter(DefaultParams)),
+				WithLegacyScheme("page-number", PageNumberRewriter(DefaultParams, 20, 100)),
+			},
+			url:          "http://example.com/items?page=3",
+			expectScheme: "page-number",
+			expectOffset: 40,
+		},
+		{
+			name: "not rewritten",
+			opts: []MiddlewareOpt{
+				WithLegacyScheme("offset", OffsetLimitRewriter(DefaultParams)),
+				WithLegacyScheme("page-number", PageNumberRewriter(DefaultParams, 20, 100)),
+			},
+			url:          "http://example.com/items?page=" + OffsetToken(10),
+			expectScheme: "",
+			expectOffset: 10,
+		},
+		{
+			name: "unnamed schemes are additive",
+			opts: []MiddlewareOpt{
+				WithBackwardsCompatibility(OffsetLimitRewriter(DefaultParams)),
+				WithBackwardsCompatibility(PageNumberRewriter(DefaultParams, 20, 100)),
+			},
+			url:          "http://example.com/items?page=3",
+			expectScheme: "",
+			expectOffset: 40,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m   = NewMiddleware(tt.opts...)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.expectScheme, LegacyScheme(r))
+
+				offset, err := Offset(r)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectOffset, offset)
+			})).ServeHTTP(rec, req)
+		})
+	}
+}
+
+func TestOffsetLimitResponder(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		links         map[string]string
+		expectHeaders map[string][]string
+	}{
+		{
+			name:  "not rewritten",
+			url:   "http://example.com/items?maxItems=10",
+			links: map[string]string{"next": OffsetToken(10)},
+			expectHeaders: map[string][]string{
+				"Content-Type": {"text/plain; charset=utf-8"},
+				"Link":         {`<http://example.com/items?maxItems=10&page=` + OffsetToken(10) + `>; rel="next"`},
+			},
+		},
+		{
+			name: "rewritten",
+			url:  "http://example.com/items?offset=20&limit=10&status=open",
+			links: map[string]string{
+				"next":  OffsetToken(30),
+				"prev":  OffsetToken(10),
+				"first": "",
+			},
+			expectHeaders: map[string][]string{
+				"Content-Type":  {"text/plain; charset=utf-8"},
+				"X-Total-Count": {"95"},
+				"Link": {
+					`<http://example.com/items?maxItems=10&page=` + OffsetToken(20) + `&status=open>; rel="alternate"`,
+					`<http://example.com/items?limit=10&offset=30&status=open>; rel="next"`,
+					`<http://example.com/items?limit=10&offset=10&status=open>; rel="prev"`,
+					`<http://example.com/items?limit=10&status=open>; rel="first"`,
+				},
+				"Warning": {`299 - "Deprecated pagination method. Please use alternate method."`},
+			},
+		},
+		{
+			name:  "rewritten with foreign token",
+			url:   "http://example.com/items?offset=20&limit=10",
+			links: map[string]string{"next": "abc"},
+			expectHeaders: map[string][]string{
+				"Content-Type":  {"text/plain; charset=utf-8"},
+				"X-Total-Count": {"95"},
+				"Link": {
+					`<http://example.com/items?maxItems=10&page=` + OffsetToken(20) + `>; rel="alternate"`,
+					`<http://example.com/items?maxItems=10&page=abc>; rel="next"`,
+				},
+				"Warning": {`299 - "Deprecated pagination method. Please use alternate method."`},
+			},
+		},
+		{
+			name:  "rewritten with foreign json token",
+			url:   "http://example.com/items?offset=20&limit=10",
+			links: map[string]string{"next": mustEncodeToken(t, testCursor{ID: "abc"})},
+			expectHeaders: map[string][]string{
+				"Content-Type":  {"text/plain; charset=utf-8"},
+				"X-Total-Count": {"95"},
+				"Link": {
+					`<http://example.com/items?maxItems=10&page=` + OffsetToken(20) + `>; rel="alternate"`,
+					`<http://example.com/items?maxItems=10&page=` + mustEncodeToken(t, testCursor{ID: "abc"}) + `>; rel="next"`,
+				},
+				"Warning": {`299 - "Deprecated pagination method. Please use alternate method."`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				m = NewMiddleware(
+					WithLegacyScheme("offset", OffsetLimitRewriter(DefaultParams)),
+					WithLegacyResponder("offset", func(resp LegacyResponse) {
+						offset, err := Offset(resp.Request)
+						require.NoError(t, err)
+						assert.Equal(t, 20, offset)
+
+						resp.Header.Set("X-Total-Count", "95")
+						OffsetLimitResponder(DefaultParams)(resp)
+					}),
+				)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest("GET", tt.url, nil)
+			)
+
+			m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for rel, page := range tt.links {
+					setLink(r, rel, page)
+				}
+
+				w.Write([]byte("test"))
+			})).ServeHTTP(rec, req)
+
+			equalHeaders(t, http.Header(tt.expectHeaders), rec.Header())
+		})
+	}
+}
+
+func mustEncodeToken(t *testing.T, container interface{}) string {
+	token, err := EncodeToken(container)
+	require.NoError(t, err)
+
+	return token
+}
@@ -1,6 +1,8 @@
 package pagination
 
 import (
+	"encoding/base64"
+	"errors"
 	"net/http"
 	"net/url"
 )
@@ -11,7 +13,7 @@ func MaxItems(r *http.Request) int {
 		return 0
 	}
 
-	rtn, _ := maxItems(p.current)
+	rtn, _ := p.params.maxItems(p.current)
 	return rtn
 }
 
@@ -21,10 +23,68 @@ func Page(r *http.Request) string {
 		return ""
 	}
 
-	rtn, _ := page(p.current)
+	rtn, _ := p.params.page(p.current)
 	return rtn
 }
 
+// LegacyScheme returns the name of the legacy scheme, given to WithLegacyScheme, that the request was rewritten by.
+//
+// If the request was not rewritten, or was rewritten by an unnamed Rewriter, an empty string is returned.
+func LegacyScheme(r *http.Request) string {
+	p, ok := r.Context().Value(stateKey).(*state)
+	if !ok {
+		return ""
+	}
+
+	return p.scheme
+}
+
+// Encode encodes the container as a page token, using the format configured on the middleware.
+//
+// Without WithTokenFormat, this is equivalent to EncodeToken.
+func Encode(r *http.Request, container interface{}) (string, error) {
+	b, err := tokenFormat(r).Marshal(container)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decode decodes the page token of the request, as produced by Encode, into a value of type T.
+//
+// When there is no page token, such as on a request for the first page, the zero value of T is returned.
+// Page tokens that can't be decoded give an error wrapping ErrInvalidToken.
+func Decode[T any](r *http.Request) (T, error) {
+	var rtn T
+
+	token := Page(r)
+	if token == "" {
+		return rtn, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return rtn, errors.Join(ErrInvalidToken, err)
+	}
+
+	err = tokenFormat(r).Unmarshal(b, &rtn)
+	if err != nil && !errors.Is(err, ErrInvalidToken) {
+		err = errors.Join(ErrInvalidToken, err)
+	}
+
+	return rtn, err
+}
+
+func tokenFormat(r *http.Request) Format {
+	p, ok := r.Context().Value(stateKey).(*state)
+	if !ok {
+		return JSON
+	}
+
+	return p.format
+}
+
 func SetNext(r *http.Request, page string) {
 	setLink(r, "next", page)
 }
@@ -47,7 +107,7 @@ func setLink(r *http.Request, name, page string) {
 		return
 	}
 
-	p.links[name] = setPage(p.current, page)
+	p.links[name] = p.params.setPage(p.current, page)
 }
 
 type stateContextKey string
@@ -55,7 +115,42 @@ type stateContextKey string
 var stateKey = stateContextKey("pagination.state")
 
 type state struct {
-	current      url.URL
-	wasRewritten bool
-	links        map[string]url.URL
+	current       url.URL
+	params        Params
+	wasRewritten  bool
+	scheme        string
+	legacyHeaders http.Header
+	warning       string
+	responder     LegacyResponder
+	request       *http.Request
+	links         map[string]url.URL
+	codec         *TokenCodec
+	binding       []byte
+	format        Format
+}
+
+// openPage replaces the sealed page token of the current URL with the token originally set by the handler.
+func (s *state) openPage() error {
+	sealed, _ := s.params.page(s.current)
+	if s.codec == nil || sealed == "" {
+		return nil
+	}
+
+	b, err := s.codec.openToken(sealed, s.binding)
+	if err != nil {
+		return &ParamError{Param: s.params.Page, Value: sealed, Err: err}
+	}
+
+	s.current = s.params.setPage(s.current, string(b))
+	return nil
+}
+
+// sealPage seals the page token of a link URL, ready to be sent to the client.
+func (s *state) sealPage(u url.URL) url.URL {
+	p, _ := s.params.page(u)
+	if s.codec == nil || p == "" {
+		return u
+	}
+
+	return s.params.setPage(u, s.codec.sealToken([]byte(p), s.binding))
 }
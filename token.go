@@ -1,8 +1,24 @@
 package pagination
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned when a page token is malformed, or has been modified since it was issued.
+	ErrInvalidToken = errors.New("pagination: invalid page token")
+
+	// ErrTokenExpired is returned when a page token is older than the TTL of the codec decoding it.
+	ErrTokenExpired = errors.New("pagination: page token has expired")
 )
 
 func DecodeToken(token string, container interface{}) error {
@@ -22,3 +38,288 @@ func EncodeToken(container interface{}) (string, error) {
 
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
+
+// TokenCodec encodes and decodes page tokens in the same manner as EncodeToken and DecodeToken,
+// but signs each token so that any modification made by the client is detected when it is decoded.
+//
+// With the WithEncryption option, the contents of each token are also encrypted so that they are
+// fully opaque to clients.
+//
+// Each token records the ID of the key used to produce it. Keys can be rotated without invalidating
+// tokens already issued to clients, by giving the new secret a new ID and passing the old key to
+// WithRetiredKeys until it is no longer in use.
+type TokenCodec struct {
+	primary  Key
+	retired  []Key
+	encrypt  bool
+	format   Format
+	ttl      time.Duration
+	observer func(keyID string)
+	now      func() time.Time
+
+	keys map[string]*codecKey
+}
+
+// Key is a secret used by a TokenCodec, identified by an ID that is embedded in every token it produces.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// NewTokenCodec returns a TokenCodec that signs tokens with HMAC-SHA256 using the given secret.
+// It panics if the secret, or that of any retired key, is empty.
+func NewTokenCodec(secret []byte, opts ...TokenCodecOpt) *TokenCodec {
+	c := &TokenCodec{
+		primary:  Key{Secret: secret},
+		format:   JSON,
+		observer: func(string) {},
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.keys = make(map[string]*codecKey, len(c.retired)+1)
+	for _, k := range c.retired {
+		c.keys[k.ID] = newCodecKey(k, c.encrypt)
+	}
+
+	// the primary key wins if a retired key was given the same ID
+	c.keys[c.primary.ID] = newCodecKey(c.primary, c.encrypt)
+
+	return c
+}
+
+type TokenCodecOpt func(*TokenCodec)
+
+// WithEncryption encrypts and authenticates tokens with AES-256-GCM instead of signing them with HMAC.
+//
+// The encryption key is derived from the codec's secret, so the secret may be of any length.
+func WithEncryption() TokenCodecOpt {
+	return func(c *TokenCodec) {
+		c.encrypt = true
+	}
+}
+
+// WithKeyID sets the ID of the secret given to NewTokenCodec. All new tokens are produced with this key.
+//
+// IDs are embedded in every token, so should be short. They must not be longer than 255 bytes.
+func WithKeyID(id string) TokenCodecOpt {
+	return func(c *TokenCodec) {
+		c.primary.ID = id
+	}
+}
+
+// WithRetiredKeys adds keys that are still accepted when decoding tokens, but are never used to produce new ones.
+func WithRetiredKeys(keys ...Key) TokenCodecOpt {
+	return func(c *TokenCodec) {
+		c.retired = append(c.retired, keys...)
+	}
+}
+
+// WithKeyObserver registers a function that is called with the key ID of every token successfully decoded.
+//
+// This can be used to monitor how often retired keys are still used, and decide when they can be removed.
+func WithKeyObserver(observer func(keyID string)) TokenCodecOpt {
+	return func(c *TokenCodec) {
+		c.observer = observer
+	}
+}
+
+// WithFormat sets the format used to serialize containers, in place of the default JSON.
+func WithFormat(f Format) TokenCodecOpt {
+	return func(c *TokenCodec) {
+		c.format = f
+	}
+}
+
+// WithTTL rejects tokens issued longer ago than the given duration with ErrTokenExpired.
+//
+// Every token records the time it was issued, so the TTL can be changed without reissuing tokens.
+func WithTTL(ttl time.Duration) TokenCodecOpt {
+	return func(c *TokenCodec) {
+		c.ttl = ttl
+	}
+}
+
+// Encode marshals the container with the codec's format and returns it as a signed (or encrypted), URL safe token.
+func (c *TokenCodec) Encode(container interface{}) (string, error) {
+	b, err := c.format.Marshal(container)
+	if err != nil {
+		return "", err
+	}
+
+	return c.sealToken(b, nil), nil
+}
+
+// Decode verifies the token and unmarshals its contents into the container.
+//
+// If the token is malformed, has been modified, or was produced with a key unknown to the codec,
+// ErrInvalidToken is returned. If the token is older than the codec's TTL, ErrTokenExpired is returned.
+func (c *TokenCodec) Decode(token string, container interface{}) error {
+	payload, err := c.openToken(token, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.format.Unmarshal(payload, container)
+}
+
+// sealToken seals the payload as a URL safe token. The binding is authenticated along with the payload,
+// but not included in the token, so the same binding must be given to openToken.
+func (c *TokenCodec) sealToken(payload, binding []byte) string {
+	return base64.RawURLEncoding.EncodeToString(c.seal(payload, binding))
+}
+
+func (c *TokenCodec) openToken(token string, binding []byte) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return c.open(b, binding)
+}
+
+// seal produces a token body in the form:
+//
+//	len(keyID) | keyID | issuedAt | payload | HMAC(header | len(binding) | binding | payload)
+//
+// or, when encrypting:
+//
+//	len(keyID) | keyID | issuedAt | nonce | AES-GCM(payload, header | len(binding) | binding)
+//
+// where issuedAt is the unix time the token was produced, as a uvarint.
+func (c *TokenCodec) seal(payload, binding []byte) []byte {
+	k := c.keys[c.primary.ID]
+
+	header := make([]byte, 0, 1+len(k.id)+binary.MaxVarintLen64)
+	header = append(header, byte(len(k.id)))
+	header = append(header, k.id...)
+	header = binary.AppendUvarint(header, uint64(c.now().Unix()))
+
+	ad := additionalData(header, binding)
+
+	if k.aead == nil {
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(ad)
+		mac.Write(payload)
+
+		return mac.Sum(append(header, payload...))
+	}
+
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic("pagination: failed to generate nonce: " + err.Error())
+	}
+
+	return k.aead.Seal(append(header, nonce...), nonce, payload, ad)
+}
+
+func (c *TokenCodec) open(b, binding []byte) ([]byte, error) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return nil, ErrInvalidToken
+	}
+
+	k, ok := c.keys[string(b[1:1+int(b[0])])]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	issuedAt, n := binary.Uvarint(b[1+int(b[0]):])
+	if n <= 0 {
+		return nil, ErrInvalidToken
+	}
+
+	header, body := b[:1+int(b[0])+n], b[1+int(b[0])+n:]
+
+	payload, err := k.open(additionalData(header, binding), body)
+	if err != nil {
+		return nil, err
+	}
+
+	// only trust the issued at time once the token has been authenticated
+	if c.ttl > 0 && c.now().Sub(time.Unix(int64(issuedAt), 0)) > c.ttl {
+		return nil, ErrTokenExpired
+	}
+
+	c.observer(k.id)
+	return payload, nil
+}
+
+type codecKey struct {
+	id     string
+	secret []byte
+	aead   cipher.AEAD
+}
+
+func newCodecKey(k Key, encrypt bool) *codecKey {
+	if len(k.ID) > 255 {
+		panic("pagination: key ID longer than 255 bytes")
+	}
+
+	if len(k.Secret) == 0 {
+		panic("pagination: empty key secret")
+	}
+
+	rtn := &codecKey{id: k.ID, secret: k.Secret}
+
+	if encrypt {
+		// AES-256 with a 32 byte key and the standard GCM nonce size can't fail to initialise
+		block, _ := aes.NewCipher(deriveKey(k.Secret, "encryption"))
+		rtn.aead, _ = cipher.NewGCM(block)
+	}
+
+	return rtn
+}
+
+func (k *codecKey) open(ad, body []byte) ([]byte, error) {
+	if k.aead == nil {
+		if len(body) < sha256.Size {
+			return nil, ErrInvalidToken
+		}
+
+		payload, sum := body[:len(body)-sha256.Size], body[len(body)-sha256.Size:]
+
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(ad)
+		mac.Write(payload)
+
+		if !hmac.Equal(sum, mac.Sum(nil)) {
+			return nil, ErrInvalidToken
+		}
+
+		return payload, nil
+	}
+
+	if len(body) < k.aead.NonceSize() {
+		return nil, ErrInvalidToken
+	}
+
+	nonce, ciphertext := body[:k.aead.NonceSize()], body[k.aead.NonceSize():]
+
+	payload, err := k.aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return payload, nil
+}
+
+// additionalData returns the data authenticated alongside the payload of a token.
+func additionalData(header, binding []byte) []byte {
+	ad := make([]byte, 0, len(header)+binary.MaxVarintLen64+len(binding))
+	ad = append(ad, header...)
+	ad = binary.AppendUvarint(ad, uint64(len(binding)))
+
+	return append(ad, binding...)
+}
+
+// deriveKey derives a 32 byte key for the given purpose from the secret, so that keys used by
+// different algorithms are independent of each other.
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("pagination " + purpose))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,285 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCursor struct {
+	ID     string `json:"id"`
+	Offset int    `json:"offset"`
+}
+
+func TestTokenCodec(t *testing.T) {
+	tests := []struct {
+		name        string
+		codec       *TokenCodec
+		token       func(t *testing.T) string
+		expectValue testCursor
+		expectErr   error
+	}{
+		{
+			name:  "round trip",
+			codec: NewTokenCodec([]byte("secret")),
+			token: func(t *testing.T) string {
+				return mustEncode(t, NewTokenCodec([]byte("secret")), testCursor{ID: "abc", Offset: 10})
+			},
+			expectValue: testCursor{ID: "abc", Offset: 10},
+		},
+		{
+			name:  "different secret",
+			codec: NewTokenCodec([]byte("secret")),
+			token: func(t *testing.T) string {
+				return mustEncode(t, NewTokenCodec([]byte("other")), testCursor{ID: "abc", Offset: 10})
+			},
+			expectErr: ErrInvalidToken,
+		},
+		{
+			name:  "modified payload",
+			codec: NewTokenCodec([]byte("secret")),
+			token: func(t *testing.T) string {
+				b, err := base64.RawURLEncoding.DecodeString(mustEncode(t, NewTokenCodec([]byte("secret")), testCursor{ID: "abc", Offset: 10}))
+				require.NoError(t, err)
+
+				b[0] ^= 0xff
+				return base64.RawURLEncoding.EncodeToString(b)
+			},
+			expectErr: ErrInvalidToken,
+		},
+		{
+			name:  "unsigned token",
+			codec: NewTokenCodec([]byte("secret")),
+			token: func(t *testing.T) string {
+				token, err := EncodeToken(testCursor{ID: "abc", Offset: 10})
+				require.NoError(t, err)
+				return token
+			},
+			expectErr: ErrInvalidToken,
+		},
+		{
+			name:  "encrypted round trip",
+			codec: NewTokenCodec([]byte("secret"), WithEncryption()),
+			token: func(t *testing.T) string {
+				return mustEncode(t, NewTokenCodec([]byte("secret"), WithEncryption()), testCursor{ID: "abc", Offset: 10})
+			},
+			expectValue: testCursor{ID: "abc", Offset: 10},
+		},
+		{
+			name:  "encrypted different secret",
+			codec: NewTokenCodec([]byte("secret"), WithEncryption()),
+			token: func(t *testing.T) string {
+				return mustEncode(t, NewTokenCodec([]byte("other"), WithEncryption()), testCursor{ID: "abc", Offset: 10})
+			},
+			expectErr: ErrInvalidToken,
+		},
+		{
+			name:  "encrypted modified payload",
+			codec: NewTokenCodec([]byte("secret"), WithEncryption()),
+			token: func(t *testing.T) string {
+				b, err := base64.RawURLEncoding.DecodeString(mustEncode(t, NewTokenCodec([]byte("secret"), WithEncryption()), testCursor{ID: "abc", Offset: 10}))
+				require.NoError(t, err)
+
+				b[len(b)-1] ^= 0xff
+				return base64.RawURLEncoding.EncodeToString(b)
+			},
+			expectErr: ErrInvalidToken,
+		},
+		{
+			name:  "signed token with encrypting codec",
+			codec: NewTokenCodec([]byte("secret"), WithEncryption()),
+			token: func(t *testing.T) string {
+				return mustEncode(t, NewTokenCodec([]byte("secret")), testCursor{ID: "abc", Offset: 10})
+			},
+			expectErr: ErrInvalidToken,
+		},
+		{
+			name:  "binary format round trip",
+			codec: NewTokenCodec([]byte("secret"), WithFormat(Binary)),
+			token: func(t *testing.T) string {
+				return mustEncode(t, NewTokenCodec([]byte("secret"), WithFormat(Binary)), testCursor{ID: "abc", Offset: 10})
+			},
+			expectValue: testCursor{ID: "abc", Offset: 10},
+		},
+		{
+			name:      "not base64",
+			codec:     NewTokenCodec([]byte("secret")),
+			token:     func(t *testing.T) string { return "!!!" },
+			expectErr: ErrInvalidToken,
+		},
+		{
+			name:      "too short",
+			codec:     NewTokenCodec([]byte("secret")),
+			token:     func(t *testing.T) string { return "abc" },
+			expectErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value testCursor
+
+			err := tt.codec.Decode(tt.token(t), &value)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectValue, value)
+		})
+	}
+}
+
+func TestTokenCodecEncryptionIsOpaque(t *testing.T) {
+	codec := NewTokenCodec([]byte("secret"), WithEncryption())
+
+	first := mustEncode(t, codec, testCursor{ID: "abc", Offset: 10})
+	second := mustEncode(t, codec, testCursor{ID: "abc", Offset: 10})
+	assert.NotEqual(t, first, second, "tokens should use a fresh nonce each time")
+
+	b, err := base64.RawURLEncoding.DecodeString(first)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "abc")
+}
+
+func TestTokenCodecEmptySecret(t *testing.T) {
+	assert.Panics(t, func() { NewTokenCodec(nil) })
+	assert.Panics(t, func() { NewTokenCodec([]byte{}) })
+	assert.Panics(t, func() { NewTokenCodec([]byte("secret"), WithRetiredKeys(Key{ID: "old"})) })
+}
+
+func TestTokenCodecKeyRotation(t *testing.T) {
+	var (
+		oldKey = Key{ID: "2023", Secret: []byte("old secret")}
+		newKey = Key{ID: "2024", Secret: []byte("new secret")}
+	)
+
+	tests := []struct {
+		name           string
+		encodeWith     *TokenCodec
+		decodeWith     func(observer func(string)) *TokenCodec
+		expectErr      error
+		expectObserved []string
+	}{
+		{
+			name:       "primary key",
+			encodeWith: NewTokenCodec(newKey.Secret, WithKeyID(newKey.ID)),
+			decodeWith: func(observer func(string)) *TokenCodec {
+				return NewTokenCodec(newKey.Secret, WithKeyID(newKey.ID), WithRetiredKeys(oldKey), WithKeyObserver(observer))
+			},
+			expectObserved: []string{"2024"},
+		},
+		{
+			name:       "retired key",
+			encodeWith: NewTokenCodec(oldKey.Secret, WithKeyID(oldKey.ID)),
+			decodeWith: func(observer func(string)) *TokenCodec {
+				return NewTokenCodec(newKey.Secret, WithKeyID(newKey.ID), WithRetiredKeys(oldKey), WithKeyObserver(observer))
+			},
+			expectObserved: []string{"2023"},
+		},
+		{
+			name:       "encrypted retired key",
+			encodeWith: NewTokenCodec(oldKey.Secret, WithKeyID(oldKey.ID), WithEncryption()),
+			decodeWith: func(observer func(string)) *TokenCodec {
+				return NewTokenCodec(newKey.Secret, WithKeyID(newKey.ID), WithRetiredKeys(oldKey), WithEncryption(), WithKeyObserver(observer))
+			},
+			expectObserved: []string{"2023"},
+		},
+		{
+			name:       "removed key",
+			encodeWith: NewTokenCodec(oldKey.Secret, WithKeyID(oldKey.ID)),
+			decodeWith: func(observer func(string)) *TokenCodec {
+				return NewTokenCodec(newKey.Secret, WithKeyID(newKey.ID), WithKeyObserver(observer))
+			},
+			expectErr: ErrInvalidToken,
+		},
+		{
+			name:       "reused key ID",
+			encodeWith: NewTokenCodec(oldKey.Secret, WithKeyID(newKey.ID)),
+			decodeWith: func(observer func(string)) *TokenCodec {
+				return NewTokenCodec(newKey.Secret, WithKeyID(newKey.ID), WithRetiredKeys(oldKey), WithKeyObserver(observer))
+			},
+			expectErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				observed []string
+				value    testCursor
+			)
+
+			codec := tt.decodeWith(func(keyID string) { observed = append(observed, keyID) })
+
+			err := codec.Decode(mustEncode(t, tt.encodeWith, testCursor{ID: "abc"}), &value)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, testCursor{ID: "abc"}, value)
+			}
+
+			assert.Equal(t, tt.expectObserved, observed)
+		})
+	}
+}
+
+func TestTokenCodecTTL(t *testing.T) {
+	issued := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		decodedAt time.Time
+		expectErr error
+	}{
+		{
+			name:      "no ttl",
+			decodedAt: issued.Add(365 * 24 * time.Hour),
+		},
+		{
+			name:      "within ttl",
+			ttl:       time.Hour,
+			decodedAt: issued.Add(59 * time.Minute),
+		},
+		{
+			name:      "after ttl",
+			ttl:       time.Hour,
+			decodedAt: issued.Add(61 * time.Minute),
+			expectErr: ErrTokenExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder := NewTokenCodec([]byte("secret"))
+			encoder.now = func() time.Time { return issued }
+
+			decoder := NewTokenCodec([]byte("secret"), WithTTL(tt.ttl))
+			decoder.now = func() time.Time { return tt.decodedAt }
+
+			var value testCursor
+
+			err := decoder.Decode(mustEncode(t, encoder, testCursor{ID: "abc"}), &value)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testCursor{ID: "abc"}, value)
+		})
+	}
+}
+
+func mustEncode(t *testing.T, codec *TokenCodec, value interface{}) string {
+	token, err := codec.Encode(value)
+	require.NoError(t, err)
+
+	return token
+}
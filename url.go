@@ -5,8 +5,20 @@ import (
 	"strconv"
 )
 
-func maxItems(u url.URL) (int, bool) {
-	maxItems, err := strconv.Atoi(u.Query().Get("maxItems"))
+// Params names the query parameters used for pagination.
+type Params struct {
+	MaxItems string
+	Page     string
+}
+
+// DefaultParams are the query parameter names given by the specification.
+var DefaultParams = Params{
+	MaxItems: "maxItems",
+	Page:     "page",
+}
+
+func (p Params) maxItems(u url.URL) (int, bool) {
+	maxItems, err := strconv.Atoi(u.Query().Get(p.MaxItems))
 	if err != nil {
 		return 0, false
 	}
@@ -14,21 +26,21 @@ func maxItems(u url.URL) (int, bool) {
 	return maxItems, true
 }
 
-func setMaxItems(u url.URL, maxItems int) url.URL {
+func (p Params) setMaxItems(u url.URL, maxItems int) url.URL {
 	q := u.Query()
-	q.Set("maxItems", strconv.Itoa(maxItems))
+	q.Set(p.MaxItems, strconv.Itoa(maxItems))
 
 	u.RawQuery = q.Encode()
 	return u
 }
 
-func page(u url.URL) (string, bool) {
-	return u.Query().Get("page"), true
+func (p Params) page(u url.URL) (string, bool) {
+	return u.Query().Get(p.Page), true
 }
 
-func setPage(u url.URL, page string) url.URL {
+func (p Params) setPage(u url.URL, page string) url.URL {
 	q := u.Query()
-	q.Set("page", page)
+	q.Set(p.Page, page)
 
 	u.RawQuery = q.Encode()
 	return u
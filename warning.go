@@ -0,0 +1,100 @@
+package pagination
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultWarning is the message of the Warning header added to responses to rewritten requests.
+const defaultWarning = "Deprecated pagination method. Please use alternate method."
+
+// WithWarningMessage sets the function used to choose the message of the Warning header
+// added to responses to requests rewritten by a legacy scheme.
+func WithWarningMessage(message func(*http.Request) string) MiddlewareOpt {
+	return func(m *middleware) {
+		m.warning = message
+	}
+}
+
+// WithWarningCatalog localises the message of the Warning header added to responses to requests rewritten
+// by a legacy scheme, using the request's Accept-Language header.
+//
+// The catalog maps language tags, such as "en" or "pt-BR", to messages. A request for a regional variant
+// that isn't in the catalog is given the message for the base language. The fallback language is used
+// when none of the acceptable languages are in the catalog.
+func WithWarningCatalog(catalog map[string]string, fallback string) MiddlewareOpt {
+	messages := make(map[string]string, len(catalog))
+	for tag, message := range catalog {
+		messages[strings.ToLower(tag)] = message
+	}
+
+	return WithWarningMessage(func(r *http.Request) string {
+		for _, tag := range acceptedLanguages(r.Header.Get("Accept-Language")) {
+			if message, ok := messages[tag]; ok {
+				return message
+			}
+
+			if base, _, ok := strings.Cut(tag, "-"); ok {
+				if message, ok := messages[base]; ok {
+					return message
+				}
+			}
+		}
+
+		if message, ok := messages[strings.ToLower(fallback)]; ok {
+			return message
+		}
+
+		return defaultWarning
+	})
+}
+
+// acceptedLanguages returns the lower cased language tags of an Accept-Language header,
+// most preferred first, omitting the wildcard and any tags that are not acceptable.
+func acceptedLanguages(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []language
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		if quality > 0 {
+			languages = append(languages, language{tag: tag, quality: quality})
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := make([]string, len(languages))
+	for i, l := range languages {
+		tags[i] = l.tag
+	}
+
+	return tags
+}
+
+// warningHeader formats a Warning header value with code 299, quoting the message as required.
+func warningHeader(message string) string {
+	return `299 - "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(message) + `"`
+}